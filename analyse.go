@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ParthPant/gochess/core"
+)
+
+// runAnalyse searches a position given as a FEN and prints the best lines.
+// Usage: gochess analyse [-depth n] [-multipv n] [-threads n] [-stats] [fen]
+func runAnalyse(args []string) int {
+	fs := flag.NewFlagSet("analyse", flag.ExitOnError)
+	depth := fs.Uint("depth", 5, "depth to search to")
+	multiPV := fs.Int("multipv", 3, "number of lines to show")
+	threads := fs.Int("threads", 1, "number of search threads")
+	stats := fs.Bool("stats", false, "print the search statistics as JSON")
+	fs.Parse(args)
+
+	fen := core.StartFen
+	if fs.NArg() > 0 {
+		fen = strings.Join(fs.Args(), " ")
+	}
+	board, err := core.BoardFromFen(fen)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ai := core.NewNegaMaxAI()
+	ai.SetDepth(uint8(*depth))
+	ai.MultiPV = *multiPV
+	ai.Threads = *threads
+	result, found := ai.GetBestMove(context.Background(), &board, nil)
+	if !found {
+		fmt.Println("No legal moves.")
+		return 0
+	}
+	fmt.Printf("depth %d seldepth %d nodes %d time %s nps %d\n",
+		result.Depth, result.SelDepth, result.Nodes, result.Time, result.NPS)
+	for i, line := range result.Lines {
+		fmt.Printf("%d. %-10s %s\n", i+1, core.ScoreToStr(line.Score), line.PVToStr())
+	}
+	if *stats {
+		out, err := json.MarshalIndent(result.Stats, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println(string(out))
+	}
+	return 0
+}
@@ -0,0 +1,179 @@
+// Command epdtest runs EPD test suites such as WAC or STS through the NegaMaxAI and
+// reports which positions it solves.
+//
+// Usage: epdtest [-depth n] [-time d] [-threads n] [-v] [file.epd ...]
+//
+// The records are read from the given files or from stdin. A position is solved when the
+// move found is one of its bm moves, none of its am moves, and for dm when the AI
+// finds a mate in at most that many moves.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ParthPant/gochess/core"
+)
+
+func main() {
+	depth := flag.Uint("depth", 0, "depth to search every position to, 0 means as deep as the time allows")
+	moveTime := flag.Duration("time", time.Second, "time to search every position for, 0 means no limit")
+	threads := flag.Int("threads", 1, "number of search threads")
+	verbose := flag.Bool("v", false, "print the solved positions too")
+	flag.Parse()
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	if *depth == 0 && *moveTime == 0 {
+		fmt.Fprintln(os.Stderr, "Either -depth or -time has to be given.")
+		os.Exit(2)
+	}
+
+	ai := core.NewNegaMaxAI()
+	ai.Threads = max(*threads, 1)
+	if *depth > 0 {
+		ai.SetDepth(uint8(min(*depth, uint(core.MaxDepth))))
+	} else {
+		ai.SetDepth(core.MaxDepth)
+	}
+
+	var inputs []io.Reader
+	for _, name := range flag.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		inputs = append(inputs, f)
+	}
+	if len(inputs) == 0 {
+		inputs = append(inputs, os.Stdin)
+	}
+
+	r := runner{ai: &ai, moveTime: *moveTime, verbose: *verbose}
+	for _, in := range inputs {
+		if err := r.run(in); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	r.printTotals()
+	if r.solved < r.total {
+		os.Exit(1)
+	}
+}
+
+type runner struct {
+	ai       *core.NegaMaxAI
+	moveTime time.Duration
+	verbose  bool
+
+	total, solved, invalid int
+	nodes                  uint64
+	elapsed                time.Duration
+}
+
+func (r *runner) run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r.total++
+		epd, err := core.ParseEPD(line)
+		if err != nil {
+			r.invalid++
+			fmt.Printf("INVALID %s: %s\n", line, err)
+			continue
+		}
+		r.test(epd)
+	}
+	return scanner.Err()
+}
+
+// test searches a single position and prints whether the AI solved it.
+func (r *runner) test(epd core.EPD) {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if r.moveTime > 0 {
+		ctx, cancel = context.WithTimeout(ctx, r.moveTime)
+	}
+	defer cancel()
+	r.ai.ClearHash()
+	result, found := r.ai.GetBestMove(ctx, &epd.Board, nil)
+	r.nodes += result.Nodes
+	r.elapsed += result.Time
+
+	ok := found
+	if len(epd.BestMoves) > 0 && !slices.Contains(epd.BestMoves, result.Move) {
+		ok = false
+	}
+	if slices.Contains(epd.AvoidMoves, result.Move) {
+		ok = false
+	}
+	if epd.DirectMate > 0 {
+		moves, mate := core.MateDistance(result.Score)
+		ok = ok && mate && moves > 0 && moves <= epd.DirectMate
+	}
+	if ok {
+		r.solved++
+	}
+	if ok && !r.verbose {
+		return
+	}
+
+	status := "FAIL"
+	if ok {
+		status = "PASS"
+	}
+	move := "none"
+	if found {
+		move = epd.Board.ToSAN(result.Move)
+	}
+	var expected []string
+	if len(epd.BestMoves) > 0 {
+		expected = append(expected, "bm "+strings.Join(sans(epd.Board, epd.BestMoves), " "))
+	}
+	if len(epd.AvoidMoves) > 0 {
+		expected = append(expected, "am "+strings.Join(sans(epd.Board, epd.AvoidMoves), " "))
+	}
+	if epd.DirectMate > 0 {
+		expected = append(expected, fmt.Sprintf("dm %d", epd.DirectMate))
+	}
+	id := epd.ID
+	if id == "" {
+		id = fmt.Sprintf("#%d", r.total)
+	}
+	fmt.Printf("%s %-20s found %-8s %-10s expected %s\n",
+		status, id, move, core.ScoreToStr(result.Score), strings.Join(expected, ", "))
+}
+
+func sans(b core.Board, moves []core.Move) []string {
+	s := make([]string, len(moves))
+	for i, m := range moves {
+		s[i] = b.ToSAN(m)
+	}
+	return s
+}
+
+func (r *runner) printTotals() {
+	fmt.Println("===========================")
+	fmt.Printf("Solved          : %d/%d", r.solved, r.total)
+	if r.total > 0 {
+		fmt.Printf(" (%.1f%%)", 100*float64(r.solved)/float64(r.total))
+	}
+	fmt.Println()
+	if r.invalid > 0 {
+		fmt.Printf("Invalid records : %d\n", r.invalid)
+	}
+	fmt.Printf("Nodes searched  : %d\n", r.nodes)
+	fmt.Printf("Total time (ms) : %d\n", r.elapsed.Milliseconds())
+}
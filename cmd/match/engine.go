@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ParthPant/gochess/core"
+	"github.com/ParthPant/gochess/uci"
+)
+
+// engine is one side of the match.
+type engine struct {
+	name string
+	ai   core.AI
+	// newGame is called before every game, close once the match is over.
+	newGame func() error
+	close   func() error
+}
+
+// engineSpecs collects the -engine flags.
+type engineSpecs []string
+
+func (s *engineSpecs) String() string {
+	return strings.Join(*s, " ")
+}
+
+func (s *engineSpecs) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// newEngine creates an engine from a comma separated list of key=value pairs.
+//
+//	cmd=negamax|mcts|<path to a UCI engine>  the AI to play with, negamax by default
+//	name=<name>                               the name shown in the results
+//	depth=<n>                                 the depth to search to
+//	threads=<n>, hash=<MB>, skill=<n>         settings of negamax
+//	iterations=<n>, threads=<n>               settings of mcts
+//	option.<name>=<value>                     an option sent to a UCI engine
+//
+// Unless timed is set, engines without a depth search to core.DefaultDepth.
+func newEngine(spec string, timed bool) (*engine, error) {
+	settings := map[string]string{"cmd": "negamax"}
+	var options [][2]string
+	for _, pair := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("Invalid engine setting: %s", pair)
+		}
+		if name, ok := strings.CutPrefix(key, "option."); ok {
+			options = append(options, [2]string{name, value})
+			continue
+		}
+		settings[key] = value
+	}
+	number := func(key string, fallback int) (int, error) {
+		value, ok := settings[key]
+		if !ok {
+			return fallback, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid %s: %s", key, value)
+		}
+		return n, nil
+	}
+	depth, err := number("depth", 0)
+	if err != nil {
+		return nil, err
+	}
+	if depth == 0 && !timed {
+		depth = int(core.DefaultDepth)
+	}
+	threads, err := number("threads", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(options) > 0 && (settings["cmd"] == "negamax" || settings["cmd"] == "mcts") {
+		return nil, errors.New("Options can only be set for UCI engines.")
+	}
+
+	e := &engine{
+		name:    settings["name"],
+		newGame: func() error { return nil },
+		close:   func() error { return nil },
+	}
+	switch settings["cmd"] {
+	case "negamax":
+		hash, err := number("hash", core.DefaultHashSize)
+		if err != nil {
+			return nil, err
+		}
+		skill, err := number("skill", core.MaxSkillLevel)
+		if err != nil {
+			return nil, err
+		}
+		ai := core.NewNegaMaxAI()
+		ai.Threads = threads
+		ai.SetHashSize(hash)
+		ai.SetStrength(core.NewStrength(skill))
+		// without a depth the search is only limited by the time control
+		ai.SetDepth(core.MaxDepth)
+		if depth > 0 {
+			ai.SetDepth(uint8(min(depth, int(core.MaxDepth))))
+		}
+		e.ai = &ai
+		e.newGame = func() error {
+			ai.ClearHash()
+			return nil
+		}
+	case "mcts":
+		iterations, err := number("iterations", 0)
+		if err != nil {
+			return nil, err
+		}
+		ai := core.NewMCTSAI()
+		ai.Threads = threads
+		if iterations > 0 {
+			ai.Iterations = iterations
+		}
+		e.ai = &ai
+	default:
+		client, err := uci.StartClient(settings["cmd"])
+		if err != nil {
+			return nil, fmt.Errorf("Could not start %s: %w", settings["cmd"], err)
+		}
+		for _, option := range options {
+			if err := client.SetOption(option[0], option[1]); err != nil {
+				client.Close()
+				return nil, err
+			}
+		}
+		client.Depth = depth
+		e.ai = client
+		e.newGame = client.NewGame
+		e.close = client.Close
+		if e.name == "" {
+			e.name = client.Name
+		}
+	}
+	if e.name == "" {
+		e.name = settings["cmd"]
+	}
+	return e, nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ParthPant/gochess/core"
+)
+
+// maxGamePlies ends games which are still going on after so many half moves as a draw.
+const maxGamePlies = 1000
+
+type gameResult int
+
+const (
+	whiteWins gameResult = iota
+	blackWins
+	draw
+)
+
+func (r gameResult) String() string {
+	switch r {
+	case whiteWins:
+		return "1-0"
+	case blackWins:
+		return "0-1"
+	default:
+		return "1/2-1/2"
+	}
+}
+
+// winnerIs returns the result of a game won by color.
+func winnerIs(color core.Color) gameResult {
+	if color == core.White {
+		return whiteWins
+	}
+	return blackWins
+}
+
+// adjudication ends games early once the engines agree on their outcome.
+type adjudication struct {
+	// a game is drawn when both engines score it within drawScore of zero for drawMoves
+	// moves each, but not before drawMoveNumber.
+	drawMoveNumber int
+	drawMoves      int
+	drawScore      int32
+	// a game is lost by the side whose score is below -resignScore for resignMoves
+	// moves in a row while its opponent agrees.
+	resignMoves int
+	resignScore int32
+}
+
+// game is a single game of the match.
+type game struct {
+	opening core.Board
+	// players are indexed by core.Color.
+	players [2]*engine
+	tc      core.TimeControl
+	// moveTime is the time per move when there is no time control.
+	moveTime time.Duration
+	adjudication
+
+	moves  []string
+	result gameResult
+	reason string
+}
+
+// play plays the game until it ends and sets its result.
+func (g *game) play() {
+	board := g.opening
+	repetitions := map[uint64]int{board.Hash(): 1}
+	var clock *core.Clock
+	if len(g.tc) > 0 {
+		clock = core.NewClock(g.tc, nil)
+		clock.Start(board.GetActiveColor())
+	}
+	// lastScore is the score of the last move of each side, resignCount the number of
+	// moves in a row it was lost and drawCount the number of half moves it was a draw
+	lastScore := [2]int32{}
+	resignCount := [2]int{}
+	drawCount := 0
+
+	for ply := 0; ; ply++ {
+		side := board.GetActiveColor()
+		switch {
+		case board.IsCheckmate():
+			g.end(winnerIs(1^side), "checkmate")
+			return
+		case board.IsStalemate():
+			g.end(draw, "stalemate")
+			return
+		case board.IsInsufficientMaterial():
+			g.end(draw, "insufficient material")
+			return
+		case board.IsFiftyMoveDraw():
+			g.end(draw, "fifty move rule")
+			return
+		case repetitions[board.Hash()] >= 3:
+			g.end(draw, "threefold repetition")
+			return
+		case ply >= maxGamePlies:
+			g.end(draw, "move limit")
+			return
+		}
+
+		player := g.players[side]
+		ctx, cancel := context.Background(), context.CancelFunc(func() {})
+		if clock != nil {
+			ctx, cancel = context.WithTimeout(ctx, clock.Budget(side))
+		} else if g.moveTime > 0 {
+			ctx, cancel = context.WithTimeout(ctx, g.moveTime)
+		}
+		result, found := player.ai.GetBestMove(ctx, &board, nil)
+		cancel()
+
+		if clock != nil && !clock.Press() {
+			g.end(winnerIs(1^side), fmt.Sprintf("%s loses on time", player.name))
+			return
+		}
+		if !found {
+			g.end(winnerIs(1^side), fmt.Sprintf("%s did not move", player.name))
+			return
+		}
+		next, err := board.MakeMove(result.Move)
+		if err != nil {
+			g.end(winnerIs(1^side), fmt.Sprintf("%s made an illegal move %s", player.name, result.Move.ToStr()))
+			return
+		}
+		g.moves = append(g.moves, board.ToSAN(result.Move))
+		slog.Debug("Move made.", "player", player.name, "move", result.Move.ToStr(),
+			"score", core.ScoreToStr(result.Score), "depth", result.Depth)
+		board = next
+		repetitions[board.Hash()]++
+
+		// adjudication, the scores are from the point of view of the side which moved
+		lastScore[side] = result.Score
+		if g.resignMoves > 0 {
+			if result.Score <= -g.resignScore {
+				resignCount[side]++
+			} else {
+				resignCount[side] = 0
+			}
+			if resignCount[side] >= g.resignMoves && lastScore[side^1] >= g.resignScore {
+				g.end(winnerIs(1^side), fmt.Sprintf("%s resigns", player.name))
+				return
+			}
+		}
+		if g.drawMoves > 0 && int(board.FullMoveNumber()) >= g.drawMoveNumber {
+			if result.Score >= -g.drawScore && result.Score <= g.drawScore {
+				drawCount++
+			} else {
+				drawCount = 0
+			}
+			if drawCount >= 2*g.drawMoves {
+				g.end(draw, "adjudicated draw")
+				return
+			}
+		}
+	}
+}
+
+func (g *game) end(result gameResult, reason string) {
+	g.result = result
+	g.reason = reason
+}
+
+// movesToStr returns the moves of the game numbered like in PGN.
+func (g *game) movesToStr() string {
+	var buf strings.Builder
+	number := int(g.opening.FullMoveNumber())
+	if g.opening.GetActiveColor() == core.Black {
+		fmt.Fprintf(&buf, "%d... ", number)
+	}
+	for i, san := range g.moves {
+		white := (i%2 == 0) == (g.opening.GetActiveColor() == core.White)
+		if white {
+			fmt.Fprintf(&buf, "%d. ", number)
+		}
+		buf.WriteString(san + " ")
+		if !white {
+			number++
+		}
+	}
+	buf.WriteString(g.result.String())
+	return buf.String()
+}
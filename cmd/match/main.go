@@ -0,0 +1,229 @@
+// Command match plays two engines against each other to measure which one is stronger.
+//
+// Usage: match -engine <spec> -engine <spec> [flags] [openings file]
+//
+// An engine is either one of the built-in AIs or an external UCI engine, see newEngine
+// for the format of the spec. Every opening is played twice with the colours reversed.
+// The openings are FENs or EPD records, one per line. The match ends after -rounds game
+// pairs or as soon as the SPRT accepts one of its hypotheses.
+//
+// Example: match -engine name=new,depth=5 -engine name=old,cmd=./old-gochess,option.Hash=64 -tc 10+0.1 -sprt 0,10
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ParthPant/gochess/core"
+)
+
+// defaultOpenings are played when no openings file is given.
+var defaultOpenings = []string{
+	"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2",
+	"rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+	"rnbqkbnr/pppp1ppp/4p3/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+	"rnbqkbnr/pp1ppppp/2p5/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+	"rnbqkbnr/ppp1pppp/8/3p4/2PP4/8/PP2PPPP/RNBQKBNR b KQkq - 0 2",
+	"rnbqkb1r/pppppppp/5n2/8/3P4/8/PPP1PPPP/RNBQKBNR w KQkq - 1 2",
+	"rnbqkbnr/pppppppp/8/8/2P5/8/PP1PPPPP/RNBQKBNR b KQkq - 0 1",
+	"rnbqkbnr/pppppppp/8/8/8/5N2/PPPPPPPP/RNBQKB1R b KQkq - 1 1",
+	"r1bqkbnr/pppp1ppp/2n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 3 3",
+	"rnbqkb1r/pppp1ppp/5n2/4p3/2P5/2N5/PP1PPPPP/R1BQKBNR w KQkq - 2 3",
+}
+
+func main() {
+	var specs engineSpecs
+	flag.Var(&specs, "engine", "an engine of the match, has to be given twice")
+	rounds := flag.Int("rounds", 50, "number of game pairs to play")
+	tcFlag := flag.String("tc", "", "time control as in the PGN TimeControl tag, e.g. 40/60 or 10+0.1")
+	moveTime := flag.Duration("movetime", 0, "time per move when there is no time control")
+	drawMoveNumber := flag.Int("draw-movenumber", 40, "move number from which draws are adjudicated")
+	drawMoves := flag.Int("draw-moves", 8, "moves each side has to score a draw to adjudicate it, 0 disables it")
+	drawScore := flag.Int("draw-score", 10, "score in centipawns within which a game is a draw")
+	resignMoves := flag.Int("resign-moves", 3, "moves a side has to score a loss to resign, 0 disables it")
+	resignScore := flag.Int("resign-score", 800, "score in centipawns below which a side resigns")
+	sprtFlag := flag.String("sprt", "", "run an SPRT of the Elo difference of the first engine, as elo0,elo1")
+	alpha := flag.Float64("alpha", 0.05, "false positive rate of the SPRT")
+	beta := flag.Float64("beta", 0.05, "false negative rate of the SPRT")
+	verbose := flag.Bool("v", false, "print the moves of every game")
+	flag.Parse()
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	var tc core.TimeControl
+	if *tcFlag != "" {
+		var err error
+		if tc, err = core.ParseTimeControl(*tcFlag); err != nil {
+			exit(err)
+		}
+	}
+	if len(specs) != 2 {
+		exit(fmt.Errorf("Two engines are needed, %d were given.", len(specs)))
+	}
+	var test *sprt
+	if *sprtFlag != "" {
+		elo0, elo1, ok := strings.Cut(*sprtFlag, ",")
+		e0, err0 := strconv.ParseFloat(elo0, 64)
+		e1, err1 := strconv.ParseFloat(elo1, 64)
+		if !ok || err0 != nil || err1 != nil || e0 >= e1 {
+			exit(fmt.Errorf("Invalid SPRT bounds: %s", *sprtFlag))
+		}
+		test = &sprt{e0, e1, *alpha, *beta}
+	}
+	openings, err := readOpenings(flag.Arg(0))
+	if err != nil {
+		exit(err)
+	}
+
+	timed := len(tc) > 0 || *moveTime > 0
+	var engines [2]*engine
+	for i, spec := range specs {
+		if engines[i], err = newEngine(spec, timed); err != nil {
+			closeEngines(engines)
+			exit(err)
+		}
+	}
+	defer closeEngines(engines)
+	if engines[0].name == engines[1].name {
+		engines[1].name += " 2"
+	}
+
+	adjudicate := adjudication{
+		drawMoveNumber: *drawMoveNumber,
+		drawMoves:      *drawMoves,
+		drawScore:      int32(*drawScore),
+		resignMoves:    *resignMoves,
+		resignScore:    int32(*resignScore),
+	}
+	var s score
+	decision := ""
+	for round := 0; round < *rounds && decision == ""; round++ {
+		opening := openings[round%len(openings)]
+		for _, firstIsWhite := range []bool{true, false} {
+			g := game{
+				opening:      opening,
+				players:      [2]*engine{engines[0], engines[1]},
+				tc:           tc,
+				moveTime:     *moveTime,
+				adjudication: adjudicate,
+			}
+			if !firstIsWhite {
+				g.players[0], g.players[1] = g.players[1], g.players[0]
+			}
+			for _, e := range engines {
+				if err := e.newGame(); err != nil {
+					closeEngines(engines)
+					exit(err)
+				}
+			}
+			g.play()
+
+			switch {
+			case g.result == draw:
+				s.draws++
+			case (g.result == whiteWins) == firstIsWhite:
+				s.wins++
+			default:
+				s.losses++
+			}
+			fmt.Printf("Game %d: %s vs %s %s {%s}\n",
+				s.games(), g.players[core.White].name, g.players[core.Black].name, g.result, g.reason)
+			if *verbose {
+				fmt.Printf("  %s\n", g.movesToStr())
+			}
+			printScore(engines, s, test)
+		}
+		if test != nil {
+			decision = test.decide(s)
+		}
+	}
+
+	fmt.Println("===========================")
+	printScore(engines, s, test)
+	switch decision {
+	case "H0":
+		fmt.Println("SPRT: H0 accepted, the first engine is not stronger.")
+	case "H1":
+		fmt.Println("SPRT: H1 accepted, the first engine is stronger.")
+	default:
+		if test != nil {
+			fmt.Println("SPRT: no decision.")
+		}
+	}
+}
+
+func printScore(engines [2]*engine, s score, test *sprt) {
+	elo, margin := s.elo()
+	fmt.Printf("Score of %s vs %s: %d - %d - %d [%.3f] %d\n",
+		engines[0].name, engines[1].name, s.wins, s.losses, s.draws, s.ratio(), s.games())
+	fmt.Printf("Elo difference: %.1f +/- %.1f, LOS: %.1f %%\n", elo, margin, 100*s.los())
+	if test != nil {
+		lower, upper := test.bounds()
+		fmt.Printf("SPRT: llr %.2f (%.2f, %.2f) [%.1f, %.1f]\n",
+			s.llr(test.elo0, test.elo1), lower, upper, test.elo0, test.elo1)
+	}
+}
+
+// readOpenings reads the FENs or EPD records of the openings file at path,
+// or returns the default openings if path is empty.
+func readOpenings(path string) ([]core.Board, error) {
+	lines := defaultOpenings
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		lines = nil
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+				lines = append(lines, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	var openings []core.Board
+	for _, line := range lines {
+		board, err := openingBoard(line)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid opening %s: %w", line, err)
+		}
+		openings = append(openings, board)
+	}
+	if len(openings) == 0 {
+		return nil, fmt.Errorf("No openings in %s.", path)
+	}
+	return openings, nil
+}
+
+// openingBoard parses an EPD record, or a FEN if the line has no operations.
+func openingBoard(line string) (core.Board, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 6 {
+		if _, err := strconv.Atoi(fields[4]); err == nil {
+			return core.BoardFromFen(line)
+		}
+	}
+	epd, err := core.ParseEPD(line)
+	return epd.Board, err
+}
+
+func closeEngines(engines [2]*engine) {
+	for _, e := range engines {
+		if e != nil {
+			e.close()
+		}
+	}
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(2)
+}
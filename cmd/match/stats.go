@@ -0,0 +1,96 @@
+package main
+
+import "math"
+
+// score counts the results of the first engine of the match.
+type score struct {
+	wins, losses, draws int
+}
+
+func (s score) games() int {
+	return s.wins + s.losses + s.draws
+}
+
+// ratio returns the points scored per game.
+func (s score) ratio() float64 {
+	if s.games() == 0 {
+		return 0.5
+	}
+	return (float64(s.wins) + float64(s.draws)/2) / float64(s.games())
+}
+
+// variance returns the variance of the result of a single game.
+func (s score) variance() float64 {
+	n := float64(s.games())
+	if n == 0 {
+		return 0
+	}
+	p := s.ratio()
+	return (float64(s.wins)*(1-p)*(1-p) + float64(s.draws)*(0.5-p)*(0.5-p) + float64(s.losses)*p*p) / n
+}
+
+// elo returns the Elo difference between the engines and the margin of its 95% confidence interval.
+func (s score) elo() (float64, float64) {
+	p := s.ratio()
+	if s.games() == 0 {
+		return 0, 0
+	}
+	stderr := math.Sqrt(s.variance() / float64(s.games()))
+	low := eloFromRatio(p - 1.959964*stderr)
+	high := eloFromRatio(p + 1.959964*stderr)
+	return eloFromRatio(p), (high - low) / 2
+}
+
+// los returns the likelihood of superiority, the probability that the first engine is stronger.
+func (s score) los() float64 {
+	if s.wins+s.losses == 0 {
+		return 0.5
+	}
+	return 0.5 * (1 + math.Erf(float64(s.wins-s.losses)/math.Sqrt(2*float64(s.wins+s.losses))))
+}
+
+// llr returns the log-likelihood ratio of the hypotheses that the first engine is elo1
+// rather than elo0 Elo stronger, using the normal approximation of the results.
+func (s score) llr(elo0 float64, elo1 float64) float64 {
+	variance := s.variance()
+	if variance == 0 {
+		return 0
+	}
+	s0, s1 := ratioFromElo(elo0), ratioFromElo(elo1)
+	return float64(s.games()) * (s1 - s0) * (2*s.ratio() - s0 - s1) / (2 * variance)
+}
+
+func eloFromRatio(p float64) float64 {
+	p = min(max(p, 1e-6), 1-1e-6)
+	return -400 * math.Log10(1/p-1)
+}
+
+func ratioFromElo(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// sprt is a sequential probability ratio test between H0: the Elo difference is elo0
+// and H1: the Elo difference is elo1.
+type sprt struct {
+	elo0, elo1  float64
+	alpha, beta float64
+}
+
+// bounds returns the log-likelihood ratios at which H0 and H1 are accepted.
+func (t sprt) bounds() (float64, float64) {
+	return math.Log(t.beta / (1 - t.alpha)), math.Log((1 - t.beta) / t.alpha)
+}
+
+// decide returns "H0" or "H1" once one of them is accepted and "" while the test goes on.
+func (t sprt) decide(s score) string {
+	lower, upper := t.bounds()
+	llr := s.llr(t.elo0, t.elo1)
+	switch {
+	case llr <= lower:
+		return "H0"
+	case llr >= upper:
+		return "H1"
+	default:
+		return ""
+	}
+}
@@ -1,54 +1,416 @@
 package core
 
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ParthPant/gochess/util"
+)
+
+// ProgressFunc receives the result of every completed iteration of a running search.
+type ProgressFunc func(SearchResult)
+
 type AI interface {
-	GetBestMove(b *Board) (Move, bool)
+	// GetBestMove searches b until it is done or ctx is cancelled, in which case
+	// the best move found so far is returned.
+	GetBestMove(ctx context.Context, b *Board, progress ProgressFunc) (SearchResult, bool)
+}
+
+// SearchOptions toggles the selective parts of the search.
+// They can be switched off individually to measure how much each of them contributes.
+type SearchOptions struct {
+	// PVS searches all but the first move with a zero window and re-searches on a fail-high.
+	PVS bool `json:"pvs"`
+	// NullMove prunes nodes where passing the move still fails high.
+	NullMove bool `json:"null_move"`
+	// LMR reduces the depth of quiet moves ordered late in the move list.
+	LMR bool `json:"lmr"`
+	// CheckExtensions searches moves giving check one ply deeper.
+	CheckExtensions bool `json:"check_extensions"`
+	// ReverseFutility prunes nodes near the horizon whose static evaluation is far above beta.
+	ReverseFutility bool `json:"reverse_futility"`
+	// Futility skips quiet moves near the horizon when the static evaluation is far below alpha.
+	Futility bool `json:"futility"`
+}
+
+func DefaultSearchOptions() SearchOptions {
+	return SearchOptions{
+		PVS:             true,
+		NullMove:        true,
+		LMR:             true,
+		CheckExtensions: true,
+		ReverseFutility: true,
+		Futility:        true,
+	}
+}
+
+// PVLine is a principal variation along with its score.
+type PVLine struct {
+	PV    []Move
+	Score int32
+}
+
+// PVToStr returns the principal variation as a space separated list of moves.
+func (l *PVLine) PVToStr() string {
+	moves := make([]string, len(l.PV))
+	for i, m := range l.PV {
+		moves[i] = m.ToStr()
+	}
+	return strings.Join(moves, " ")
+}
+
+// SearchResult is the outcome of a search.
+type SearchResult struct {
+	// Move is the best move found, it is always the first move of PV.
+	Move Move
+	// PV is the principal variation, the line the engine expects to be played.
+	PV []Move
+	// Score is the evaluation from the point of view of the side to move.
+	Score int32
+	// Lines holds the best lines ranked by their score when searching with MultiPV.
+	// The first line is the same as PV unless the strength of the AI is limited,
+	// in which case PV may be one of the weaker lines.
+	Lines []PVLine
+	// Depth is the last fully completed iteration.
+	Depth int
+	// SelDepth is the deepest ply reached including extensions and quiescence.
+	SelDepth int
+	Nodes    uint64
+	Time     time.Duration
+	// NPS is the number of nodes searched per second.
+	NPS uint64
+	// Stats is only filled in by the NegaMaxAI once the search has finished.
+	Stats SearchStats
+}
+
+// PVToStr returns the principal variation as a space separated list of moves.
+func (r *SearchResult) PVToStr() string {
+	line := PVLine{r.PV, r.Score}
+	return line.PVToStr()
+}
+
+// ScoreToStr formats a score either in centipawns or as the number of moves to mate.
+func ScoreToStr(score int32) string {
+	if moves, ok := MateDistance(score); ok {
+		return fmt.Sprintf("mate %d", moves)
+	}
+	return fmt.Sprintf("cp %d", score)
 }
 
+// MateDistance returns the number of moves until mate of a mate score, it is negative
+// when the side to move gets mated. It returns false if score is not a mate score.
+func MateDistance(score int32) (int, bool) {
+	if score >= -MatingScore-maxPly {
+		return int(-MatingScore-score+1) / 2, true
+	} else if score <= MatingScore+maxPly {
+		return -int(score-MatingScore) / 2, true
+	}
+	return 0, false
+}
+
+// MateScore is the inverse of MateDistance. It returns the score of a mate in moves
+// moves, which is negative when the side to move gets mated.
+func MateScore(moves int) int32 {
+	if moves > 0 {
+		return -MatingScore - int32(2*moves-1)
+	}
+	return MatingScore + int32(-2*moves)
+}
+
+// DefaultDepth is the depth searched by a new NegaMaxAI.
+const DefaultDepth uint8 = 5
+
+// MaxDepth is the deepest iteration the search can be asked for. Use it with a
+// time limit on the context to search as deep as the time allows.
+const MaxDepth uint8 = maxPly - 1
+
 type NegaMaxAI struct {
 	evalMethod func(b *Board) int32
 	depth      uint8
+	Options    SearchOptions
+	// MultiPV is the number of best lines to search for. The moves found by earlier
+	// lines are excluded at the root for the following ones.
+	MultiPV int
+	// Threads is the number of goroutines searching in parallel. They share the
+	// transposition table of the AI and only the main thread reports results.
+	Threads int
+	// MaxNodes stops the search after the main thread has searched this many nodes.
+	// 0 means that the number of nodes is not limited.
+	MaxNodes uint64
+	tt       *transpositionTable
+	strength Strength
+	// prng chooses the weaker moves played when the strength is limited.
+	prng util.PRNG
 }
 
 func NewNegaMaxAI() NegaMaxAI {
-	return NegaMaxAI{
-		evaluateBoard,
-		5,
+	nmax := NegaMaxAI{
+		evalMethod: evaluateBoard,
+		depth:      DefaultDepth,
+		Options:    DefaultSearchOptions(),
+		MultiPV:    1,
+		Threads:    1,
+		tt:         newTranspositionTable(DefaultHashSize),
 	}
+	nmax.SetStrength(FullStrength())
+	return nmax
+}
+
+// SetStrength limits the playing strength of the AI and reseeds its random number generator.
+func (nmax *NegaMaxAI) SetStrength(strength Strength) {
+	nmax.strength = strength
+	nmax.prng.Seed(strength.Seed | 1)
 }
 
-func (nmax *NegaMaxAI) GetBestMove(b *Board) (Move, bool) {
-	value := MinScore
-	var bestMove Move
+func (nmax *NegaMaxAI) GetStrength() Strength {
+	return nmax.strength
+}
+
+// SetHashSize replaces the transposition table with a new one of sizeMB megabytes.
+func (nmax *NegaMaxAI) SetHashSize(sizeMB int) {
+	nmax.tt = newTranspositionTable(sizeMB)
+}
+
+// ClearHash empties the transposition table.
+func (nmax *NegaMaxAI) ClearHash() {
+	nmax.tt.clear()
+}
+
+func (nmax *NegaMaxAI) SetDepth(depth uint8) {
+	nmax.depth = depth
+}
+
+// GetBestMove searches the board with iterative deepening up to the depth of the AI.
+// The result holds the principal variations of the last completed iteration.
+// The search stops early when ctx is done, the first iteration is always completed
+// so that a move can be returned. progress may be nil.
+func (nmax *NegaMaxAI) GetBestMove(ctx context.Context, b *Board, progress ProgressFunc) (SearchResult, bool) {
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	s := newSearcher(nmax, 0, &stop)
+	s.ctx = ctx
+	helpers := make([]*searcher, max(nmax.Threads, 1)-1)
+	for i := range helpers {
+		helpers[i] = newSearcher(nmax, i+1, &stop)
+		wg.Add(1)
+		go func(h *searcher, b Board) {
+			defer wg.Done()
+			h.helperSearch(b)
+		}(helpers[i], *b)
+	}
+	maxDepth := int(nmax.depth)
+	multiPV := max(nmax.MultiPV, 1)
+	s.maxNodes = nmax.MaxNodes
+	if nmax.strength.isLimited() {
+		maxDepth = min(maxDepth, nmax.strength.depthLimit())
+		multiPV = max(multiPV, skillMultiPV)
+		s.maxNodes = nmax.strength.nodeLimit()
+		if nmax.MaxNodes > 0 {
+			s.maxNodes = min(s.maxNodes, nmax.MaxNodes)
+		}
+	}
+
+	start := time.Now()
+	var result SearchResult
+	var stats SearchStats
+	var prevLines []PVLine
 	found := false
-	for _, move := range b.getAllLegalMoves(b.activeColor) {
-		if board_copy, ok := b.makeMove(move); ok {
-			move_score := -nmax.negamax(board_copy, nmax.depth-1, MinScore, MaxScore)
-			if move_score > value {
-				value = move_score
-				bestMove = move
-				found = true
+	for depth := 1; depth <= maxDepth; depth++ {
+		lines := make([]PVLine, 0, multiPV)
+		s.excluded = s.excluded[:0]
+		for i := 0; i < multiPV; i++ {
+			s.prevPV = nil
+			if i < len(prevLines) {
+				s.prevPV = prevLines[i].PV
+			}
+			score := s.negamax(*b, depth, 0, MinScore, MaxScore, false)
+			if stop.Load() || s.pvLength[0] == 0 {
+				// either the search was interrupted or there are no more legal moves
+				break
 			}
+			line := PVLine{slices.Clone(s.pvTable[0][:s.pvLength[0]]), score}
+			lines = append(lines, line)
+			s.excluded = append(s.excluded, line.PV[0])
 		}
+		if stop.Load() || len(lines) == 0 {
+			break
+		}
+		slices.SortStableFunc(lines, func(l1 PVLine, l2 PVLine) int {
+			return int(l2.Score - l1.Score)
+		})
+		prevLines = lines
+		result = SearchResult{
+			Move:     lines[0].PV[0],
+			PV:       lines[0].PV,
+			Score:    lines[0].Score,
+			Lines:    lines,
+			Depth:    depth,
+			SelDepth: s.selDepth,
+		}
+		found = true
+		s.canStop = true
+		result.setNodes(start, s, helpers)
+		stats.addIteration(depth, result.Nodes, result.Time)
+		slog.Debug("Search iteration complete.",
+			"depth", depth, "score", ScoreToStr(result.Score), "nodes", result.Nodes,
+			"ebf", stats.Iterations[len(stats.Iterations)-1].EBF, "pv", result.PVToStr())
+		if progress != nil {
+			progress(result)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	stop.Store(true)
+	wg.Wait()
+	result.setNodes(start, s, helpers)
+	stats.collect(result.Time, append([]*searcher{s}, helpers...)...)
+	stats.log()
+	result.Stats = stats
+	if found && nmax.strength.isLimited() {
+		line := nmax.strength.pickLine(result.Lines, &nmax.prng)
+		result.Move, result.PV, result.Score = line.PV[0], line.PV, line.Score
 	}
-	return bestMove, found
+	return result, found
 }
 
-func (nmax *NegaMaxAI) negamax(b Board, depth uint8, alpha int32, beta int32) int32 {
-	if depth == 0 {
-		return nmax.evalMethod(&b)
+// setNodes sets the node count of all the threads of a search along with the time and nps.
+func (r *SearchResult) setNodes(start time.Time, s *searcher, helpers []*searcher) {
+	r.Time = time.Since(start)
+	r.Nodes = s.nodes.Load()
+	for _, h := range helpers {
+		r.Nodes += h.nodes.Load()
 	}
-	if b.isActiveSideInCheck() {
-		return MatingScore
+	if r.Time > 0 {
+		r.NPS = uint64(float64(r.Nodes) / r.Time.Seconds())
 	}
-	value := MinScore
-	for _, move := range b.getAllLegalMoves(b.activeColor) {
-		if board_copy, ok := b.makeMove(move); ok {
-			value = max(value, -nmax.negamax(board_copy, depth-1, -beta, -alpha))
-			alpha = max(alpha, value)
-			if alpha >= beta {
-				break
+}
+
+// SearchHandle is a search running in the background, see StartSearch and StartPonder.
+type SearchHandle struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	result SearchResult
+	found  bool
+
+	// released is closed once the result of the search may be used. A ponder search
+	// is only released by PonderHit or Stop, even if it finishes before.
+	released    chan struct{}
+	releaseOnce sync.Once
+
+	mu          sync.Mutex
+	progress    SearchResult
+	hasProgress bool
+	// timer cancels the search once the budget given by PonderHit is used up. It is
+	// stopped when the search finishes.
+	timer    *time.Timer
+	finished bool
+}
+
+// StartSearch runs ai on a copy of b in a new goroutine and returns immediately.
+// progress is called from the search goroutine, it may be nil.
+func StartSearch(ctx context.Context, ai AI, b Board, progress ProgressFunc) *SearchHandle {
+	h := startSearch(ctx, ai, b, progress)
+	h.release()
+	return h
+}
+
+// StartPonder starts searching b, the position after the move the AI expects its
+// opponent to reply with, while the opponent is thinking. The search keeps going
+// until PonderHit is called when the opponent played the expected move, or until
+// Stop is called when it did not.
+func StartPonder(ctx context.Context, ai AI, b Board, progress ProgressFunc) *SearchHandle {
+	return startSearch(ctx, ai, b, progress)
+}
+
+func startSearch(ctx context.Context, ai AI, b Board, progress ProgressFunc) *SearchHandle {
+	ctx, cancel := context.WithCancel(ctx)
+	h := &SearchHandle{
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		released: make(chan struct{}),
+	}
+	go func() {
+		defer close(h.done)
+		defer cancel()
+		h.result, h.found = ai.GetBestMove(ctx, &b, func(r SearchResult) {
+			h.mu.Lock()
+			h.progress = r
+			h.hasProgress = true
+			h.mu.Unlock()
+			if progress != nil {
+				progress(r)
 			}
+		})
+		<-h.released
+		h.mu.Lock()
+		h.finished = true
+		if h.timer != nil {
+			h.timer.Stop()
+		}
+		h.mu.Unlock()
+	}()
+	return h
+}
+
+func (h *SearchHandle) release() {
+	h.releaseOnce.Do(func() {
+		close(h.released)
+	})
+}
+
+// PonderHit turns a ponder search into a normal search because the opponent played
+// the expected move. The search is given budget more time to finish, a budget of 0
+// lets it run until it reaches the depth of the AI.
+func (h *SearchHandle) PonderHit(budget time.Duration) {
+	h.mu.Lock()
+	if budget > 0 && !h.finished {
+		if h.timer != nil {
+			h.timer.Stop()
 		}
+		h.timer = time.AfterFunc(budget, h.cancel)
 	}
-	return value
+	h.mu.Unlock()
+	h.release()
+}
+
+// Stop asks the search to finish early. The best move found so far is still returned by Wait.
+func (h *SearchHandle) Stop() {
+	h.cancel()
+	h.release()
+}
+
+// Done is closed once the search has finished.
+func (h *SearchHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Wait blocks until the search has finished and returns its result.
+func (h *SearchHandle) Wait() (SearchResult, bool) {
+	<-h.done
+	return h.result, h.found
+}
+
+// Poll returns the result of the search without blocking.
+// finished is false while the search is still running.
+func (h *SearchHandle) Poll() (result SearchResult, found bool, finished bool) {
+	select {
+	case <-h.done:
+		return h.result, h.found, true
+	default:
+		return SearchResult{}, false, false
+	}
+}
+
+// Progress returns the result of the last completed iteration of a running search.
+func (h *SearchHandle) Progress() (SearchResult, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.progress, h.hasProgress
 }
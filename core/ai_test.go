@@ -0,0 +1,35 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPonderHitTimerStopped(t *testing.T) {
+	b, err := BoardFromFen(StartFen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, finish := range map[string]func(h *SearchHandle){
+		"finished": func(h *SearchHandle) {},
+		"stopped":  (*SearchHandle).Stop,
+	} {
+		t.Run(name, func(t *testing.T) {
+			h := StartPonder(context.Background(), newTestAI(2), b, nil)
+			h.PonderHit(time.Hour)
+			finish(h)
+			if _, found := h.Wait(); !found {
+				t.Fatal("The search found no move.")
+			}
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if h.timer == nil {
+				t.Fatal("PonderHit did not start a timer.")
+			}
+			if h.timer.Stop() {
+				t.Error("The timer of the budget is still pending after the search finished.")
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// MoveClass rates a move by how much worse it is than the best move in the position.
+type MoveClass uint8
+
+const (
+	Best MoveClass = iota
+	Good
+	Inaccuracy
+	Mistake
+	Blunder
+)
+
+func (c MoveClass) ToStr() string {
+	switch c {
+	case Best:
+		return "Best move"
+	case Good:
+		return "Good move"
+	case Inaccuracy:
+		return "Inaccuracy"
+	case Mistake:
+		return "Mistake"
+	case Blunder:
+		return "Blunder"
+	default:
+		return fmt.Sprintf("Class %d", c)
+	}
+}
+
+// nag returns the glyph marking moves of the class in PGN. Good and best moves are not marked.
+func (c MoveClass) nag() (NAG, bool) {
+	switch c {
+	case Inaccuracy:
+		return NAGDubious, true
+	case Mistake:
+		return NAGMistake, true
+	case Blunder:
+		return NAGBlunder, true
+	}
+	return 0, false
+}
+
+// DefaultAnalysisDepth is the depth positions are searched to by a new Analyser.
+const DefaultAnalysisDepth uint8 = 8
+
+// analysisScoreLimit caps the scores the analysis compares, so that a mate counts as
+// a large advantage and missing a faster mate is not a mistake.
+const analysisScoreLimit int32 = 1000
+
+// Analyser finds out where a game went wrong. It searches every position of the game with
+// a NegaMaxAI and compares each move with the best move it finds.
+type Analyser struct {
+	// Depth is the depth each position is searched to, 0 searches until MoveTime is up.
+	// MoveTime limits the time spent on a position, 0 means no limit.
+	Depth    uint8
+	MoveTime time.Duration
+	Threads  int
+	// A move which is worse than the best move by at least Inaccuracy, Mistake or
+	// Blunder centipawns is classed as one.
+	Inaccuracy int32
+	Mistake    int32
+	Blunder    int32
+	// Progress is called after each searched position with the number of positions
+	// done so far and in total. It may be nil.
+	Progress func(done int, total int)
+}
+
+func NewAnalyser() Analyser {
+	return Analyser{
+		Depth:      DefaultAnalysisDepth,
+		Threads:    runtime.NumCPU(),
+		Inaccuracy: 50,
+		Mistake:    100,
+		Blunder:    300,
+	}
+}
+
+// PlyAnalysis is the analysis of a move. Scores are in centipawns from the point of view of White.
+type PlyAnalysis struct {
+	Move Move
+	SAN  string
+	Side Color
+	// Score is the evaluation of the position after the move.
+	Score int32
+	// BestLine is the line the engine prefers in the position before the move and
+	// BestScore the evaluation of that position.
+	BestLine  []Move
+	BestSAN   string
+	BestScore int32
+	// Loss is by how many centipawns the move is worse than the best move for the side which made it.
+	Loss  int32
+	Class MoveClass
+	// Accuracy goes from 0 for a move which throws away a won game to 100 for the best move.
+	Accuracy float64
+}
+
+// GameAnalysis is the result of analysing a game.
+type GameAnalysis struct {
+	Start Board
+	// Score is the evaluation of the starting position, from the point of view of White.
+	Score int32
+	Plies []PlyAnalysis
+	// Accuracy is the average accuracy of the moves of each side and AverageLoss
+	// their average centipawn loss.
+	Accuracy    [2]float64
+	AverageLoss [2]float64
+	// Tags and Result are written to the annotated PGN.
+	Tags   map[string]string
+	Result Outcome
+}
+
+// Analyse analyses the moves which have been played in g. It stops with the error of
+// ctx when ctx is done.
+func (a Analyser) Analyse(ctx context.Context, g *ChessGame) (*GameAnalysis, error) {
+	g.mu.Lock()
+	start, moves := g.history.Start(), g.history.Moves()
+	tags := map[string]string{"White": g.players[White].Name(), "Black": g.players[Black].Name()}
+	if g.clock != nil {
+		tags["TimeControl"] = g.clock.Control().ToStr()
+	}
+	result := g.gameResult()
+	g.mu.Unlock()
+
+	analysis, err := a.AnalyseLine(ctx, start, moves)
+	if err != nil {
+		return nil, err
+	}
+	maps.Copy(analysis.Tags, tags)
+	analysis.Result = result.Outcome
+	return analysis, nil
+}
+
+// AnalyseLine analyses moves played from start.
+func (a Analyser) AnalyseLine(ctx context.Context, start Board, moves []Move) (*GameAnalysis, error) {
+	if a.Depth == 0 && a.MoveTime == 0 {
+		return nil, errors.New("The analysis needs a depth or a time per move.")
+	}
+	boards := make([]Board, len(moves)+1)
+	boards[0] = start
+	for i, m := range moves {
+		next, err := boards[i].MakeMove(m)
+		if err != nil {
+			return nil, fmt.Errorf("Illegal move: %s", m.ToStr())
+		}
+		boards[i+1] = next
+	}
+
+	ai := NewNegaMaxAI()
+	ai.SetDepth(MaxDepth)
+	if a.Depth > 0 {
+		ai.SetDepth(min(a.Depth, MaxDepth))
+	}
+	ai.Threads = max(a.Threads, 1)
+	// the position after the last move is searched too, for the score of the last move
+	scores := make([]int32, len(boards))
+	lines := make([][]Move, len(boards))
+	for i := range boards {
+		var err error
+		if scores[i], lines[i], err = a.evaluate(ctx, &ai, &boards[i]); err != nil {
+			return nil, err
+		}
+		if a.Progress != nil {
+			a.Progress(i+1, len(boards))
+		}
+	}
+
+	analysis := &GameAnalysis{
+		Start:  start,
+		Score:  scores[0],
+		Plies:  make([]PlyAnalysis, len(moves)),
+		Tags:   map[string]string{"Annotator": "GoChess"},
+		Result: boardResult(&boards[len(moves)]).Outcome,
+	}
+	var totalLoss, totalAccuracy [2]float64
+	var count [2]int
+	for i, m := range moves {
+		side := boards[i].activeColor
+		// best and played are the scores before and after the move for the side which made it
+		best, played := clampScore(scores[i]), clampScore(scores[i+1])
+		if side == Black {
+			best, played = -best, -played
+		}
+		loss := max(best-played, 0)
+		accuracy := moveAccuracy(best, played)
+		var bestSAN string
+		if len(lines[i]) > 0 {
+			bestSAN = boards[i].ToSAN(lines[i][0])
+			if lines[i][0] == m {
+				// the move the engine prefers cannot be worse than itself
+				loss, accuracy = 0, 100
+			}
+		}
+		analysis.Plies[i] = PlyAnalysis{
+			Move:      m,
+			SAN:       boards[i].ToSAN(m),
+			Side:      side,
+			Score:     scores[i+1],
+			BestLine:  lines[i],
+			BestSAN:   bestSAN,
+			BestScore: scores[i],
+			Loss:      loss,
+			Class:     a.classify(loss),
+			Accuracy:  accuracy,
+		}
+		totalLoss[side] += float64(loss)
+		totalAccuracy[side] += accuracy
+		count[side]++
+	}
+	for _, side := range []Color{White, Black} {
+		if count[side] > 0 {
+			analysis.AverageLoss[side] = totalLoss[side] / float64(count[side])
+			analysis.Accuracy[side] = totalAccuracy[side] / float64(count[side])
+		}
+	}
+	return analysis, nil
+}
+
+// evaluate searches b and returns its score from the point of view of White and the
+// line the engine prefers. Positions in which the game is over are not searched.
+func (a Analyser) evaluate(ctx context.Context, ai *NegaMaxAI, b *Board) (int32, []Move, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, nil, err
+	}
+	var score int32
+	var line []Move
+	if result := boardResult(b); result.IsOver() {
+		if result.Termination == Checkmate {
+			score = MateScore(0)
+		}
+	} else {
+		searchCtx := ctx
+		if a.MoveTime > 0 {
+			var cancel context.CancelFunc
+			searchCtx, cancel = context.WithTimeout(ctx, a.MoveTime)
+			defer cancel()
+		}
+		result, found := ai.GetBestMove(searchCtx, b, nil)
+		if err := ctx.Err(); err != nil {
+			return 0, nil, err
+		}
+		if found {
+			score, line = result.Score, result.PV
+		}
+	}
+	if b.activeColor == Black {
+		score = -score
+	}
+	return score, line, nil
+}
+
+func (a Analyser) classify(loss int32) MoveClass {
+	switch {
+	case loss >= a.Blunder:
+		return Blunder
+	case loss >= a.Mistake:
+		return Mistake
+	case loss >= a.Inaccuracy:
+		return Inaccuracy
+	case loss > 0:
+		return Good
+	}
+	return Best
+}
+
+func clampScore(score int32) int32 {
+	return min(max(score, -analysisScoreLimit), analysisScoreLimit)
+}
+
+// winPercent converts a score in centipawns into the chance of winning, as lichess does.
+func winPercent(score int32) float64 {
+	return 50 + 50*(2/(1+math.Exp(-0.00368208*float64(score)))-1)
+}
+
+// moveAccuracy returns the accuracy of a move which changes the score of its side from
+// before to after. It depends on the winning chances lost, so that dropping a pawn costs
+// more in a balanced position than in one which is won anyway.
+func moveAccuracy(before int32, after int32) float64 {
+	lost := winPercent(before) - winPercent(after)
+	if lost <= 0 {
+		return 100
+	}
+	return min(max(103.1668*math.Exp(-0.04354*lost)-3.1669, 0), 100)
+}
+
+// Count returns the number of moves of side in class.
+func (r *GameAnalysis) Count(side Color, class MoveClass) int {
+	n := 0
+	for _, p := range r.Plies {
+		if p.Side == side && p.Class == class {
+			n++
+		}
+	}
+	return n
+}
+
+// GameTree returns the game annotated with the analysis. Every move is commented with the
+// evaluation after it. Inaccuracies, mistakes and blunders are marked with a NAG and the
+// line the engine prefers is added as a variation.
+func (r *GameAnalysis) GameTree() *GameTree {
+	t := NewGameTree(r.Start)
+	t.Tags = maps.Clone(r.Tags)
+	t.Result = r.Result
+	if len(r.Plies) > 0 {
+		t.root.Comment = fmt.Sprintf("White accuracy %.1f%%, Black accuracy %.1f%%.", r.Accuracy[White], r.Accuracy[Black])
+	}
+	for _, p := range r.Plies {
+		parent := t.Current()
+		node, err := t.AddMove(p.Move)
+		if err != nil {
+			panic("Error: The analysis has an illegal move.")
+		}
+		node.Comment = evalComment(p.Score)
+		nag, marked := p.Class.nag()
+		if !marked || len(p.BestLine) == 0 {
+			continue
+		}
+		node.AddNAG(nag)
+		node.Comment = strings.TrimSpace(fmt.Sprintf("%s %s. %s was best.", node.Comment, p.Class.ToStr(), p.BestSAN))
+		t.GoTo(parent)
+		for i, m := range p.BestLine {
+			variation, err := t.AddMove(m)
+			if err != nil {
+				break
+			}
+			if i == 0 {
+				variation.Comment = evalComment(p.BestScore)
+			}
+		}
+		t.GoTo(node)
+	}
+	return t
+}
+
+// ToPGN returns the annotated game, see GameTree.
+func (r *GameAnalysis) ToPGN() string {
+	return r.GameTree().ToPGN()
+}
+
+// evalComment formats a score from the point of view of White as the [%eval] command of
+// PGN comments, in pawns or as #n for a mate in n moves, negative when Black mates.
+// Positions which are mate already have no evaluation.
+func evalComment(score int32) string {
+	// mate scores are relative to the side which mates
+	if moves, ok := MateDistance(max(score, -score)); ok {
+		if moves == 0 {
+			return ""
+		}
+		if score < 0 {
+			moves = -moves
+		}
+		return fmt.Sprintf("[%%eval #%d]", moves)
+	}
+	return fmt.Sprintf("[%%eval %.2f]", float64(score)/100)
+}
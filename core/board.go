@@ -282,6 +282,17 @@ func (b Board) makeMove(m Move) (Board, bool) {
 			b.unsetBlackOO()
 		}
 	}
+	// a rook captured on its original square can no longer castle
+	switch m.to {
+	case A1:
+		b.unsetWhiteOOO()
+	case H1:
+		b.unsetWhiteOO()
+	case A8:
+		b.unsetBlackOOO()
+	case H8:
+		b.unsetBlackOO()
+	}
 	b.hash ^= ZobCastleKeys[b.castlingFlags]
 
 	// EP updates
@@ -304,8 +315,12 @@ func (b Board) makeMove(m Move) (Board, bool) {
 		b.hash ^= ZobEpKeys[t]
 	}
 
-	// increment move clocks
-	b.halfMoveClock += 1
+	// increment move clocks, the half move clock restarts with pawn moves and captures
+	if moving_piece == Pw || moving_piece == Pb || m.IsCapture() {
+		b.halfMoveClock = 0
+	} else {
+		b.halfMoveClock += 1
+	}
 	if b.activeColor == Black {
 		b.fullMoveClock += 1
 	}
@@ -317,6 +332,96 @@ func (b Board) makeMove(m Move) (Board, bool) {
 	return b, true
 }
 
+// makeNullMove passes the turn to the other side without moving a piece.
+func (b Board) makeNullMove() Board {
+	if t, exists := b.epTarget.get(); exists {
+		b.hash ^= ZobEpKeys[t]
+	}
+	b.epTarget.clear()
+	b.halfMoveClock += 1
+	if b.activeColor == Black {
+		b.fullMoveClock += 1
+	}
+	b.activeColor = 1 ^ b.activeColor
+	b.hash ^= ZobBlackToMoveKey
+	return b
+}
+
+// undoInfo is what unmakeMove needs besides the move to restore the position the move
+// was made in.
+type undoInfo struct {
+	captured      Piece
+	castlingFlags uint8
+	epTarget      epTarget
+	halfMoveClock uint
+	hash          uint64
+}
+
+// newUndoInfo records the state of b which is lost by making m in it.
+func (b *Board) newUndoInfo(m Move) undoInfo {
+	u := undoInfo{
+		castlingFlags: b.castlingFlags,
+		epTarget:      b.epTarget,
+		halfMoveClock: b.halfMoveClock,
+		hash:          b.hash,
+	}
+	if m.IsEp() {
+		u.captured = Pb
+		if b.activeColor == Black {
+			u.captured = Pw
+		}
+	} else if m.IsCapture() {
+		u.captured, _ = b.GetAtSq(m.to)
+	}
+	return u
+}
+
+// unmakeMove takes back m, which has been made in the position u was recorded in.
+func (b Board) unmakeMove(m Move, u undoInfo) Board {
+	b.activeColor = 1 ^ b.activeColor
+	if b.activeColor == Black {
+		b.fullMoveClock -= 1
+	}
+	king, rook, pawn := Piece(Kw), Piece(Rw), Piece(Pw)
+	if b.activeColor == Black {
+		king, rook, pawn = Kb, Rb, Pb
+	}
+	move := func(piece Piece, from Square, to Square) {
+		b.bitBoards[piece] = b.bitBoards[piece].UnSet(from).Set(to)
+	}
+
+	if m.IsKingCastle() {
+		move(king, m.to, m.from)
+		move(rook, m.to-1, m.to+1)
+	} else if m.IsQueenCastle() {
+		move(king, m.to, m.from)
+		move(rook, m.to+1, m.to-2)
+	} else if m.IsPromotion() {
+		promoted_piece := m.GetPromPiece().WithColor(b.activeColor)
+		b.bitBoards[promoted_piece] = b.bitBoards[promoted_piece].UnSet(m.to)
+		b.bitBoards[pawn] = b.bitBoards[pawn].Set(m.from)
+	} else {
+		moving_piece, _ := b.GetAtSq(m.to)
+		move(moving_piece, m.to, m.from)
+	}
+
+	if m.IsEp() {
+		captured_square := Square(m.to - 8)
+		if b.activeColor == Black {
+			captured_square = Square(m.to + 8)
+		}
+		b.bitBoards[u.captured] = b.bitBoards[u.captured].Set(captured_square)
+	} else if m.IsCapture() {
+		b.bitBoards[u.captured] = b.bitBoards[u.captured].Set(m.to)
+	}
+
+	b.castlingFlags = u.castlingFlags
+	b.epTarget = u.epTarget
+	b.halfMoveClock = u.halfMoveClock
+	b.hash = u.hash
+	return b
+}
+
 func (b *Board) isMoveLegal(m Move) bool {
 	piece, occupied := b.GetAtSq(m.from)
 	if !occupied {
@@ -372,6 +477,14 @@ func (b *Board) blackOccupancy() BitBoard {
 	return occ
 }
 
+// hasNonPawnMaterial reports whether side has any pieces other than the king and pawns.
+func (b *Board) hasNonPawnMaterial(side Color) bool {
+	if side == White {
+		return b.bitBoards[Nw]|b.bitBoards[Bw]|b.bitBoards[Rw]|b.bitBoards[Qw] > 0
+	}
+	return b.bitBoards[Nb]|b.bitBoards[Bb]|b.bitBoards[Rb]|b.bitBoards[Qb] > 0
+}
+
 func (b *Board) setWhiteOO() {
 	b.castlingFlags |= 1
 }
@@ -514,12 +627,14 @@ func (b *Board) whiteKingMoves(pos Square) BitBoard {
 	moves := KingAtkTable[pos] & ^friendly
 	allOccupancy := friendly | enemy
 	if pos == E1 && b.CanWhiteOO() {
-		if (allOccupancy&(1<<F1) == 0) && (allOccupancy&(1<<G1) == 0) && !b.isSqAttacked(G1, Black) {
+		if (allOccupancy&(1<<F1) == 0) && (allOccupancy&(1<<G1) == 0) &&
+			!b.isSqAttacked(E1, Black) && !b.isSqAttacked(F1, Black) && !b.isSqAttacked(G1, Black) {
 			moves = moves.Set(G1)
 		}
 	}
 	if pos == E1 && b.CanWhiteOOO() {
-		if (allOccupancy&(1<<D1) == 0) && (allOccupancy&(1<<C1) == 0) && (allOccupancy&(1<<B1) == 0) && !b.isSqAttacked(C1, Black) {
+		if (allOccupancy&(1<<D1) == 0) && (allOccupancy&(1<<C1) == 0) && (allOccupancy&(1<<B1) == 0) &&
+			!b.isSqAttacked(E1, Black) && !b.isSqAttacked(D1, Black) && !b.isSqAttacked(C1, Black) {
 			moves = moves.Set(C1)
 		}
 	}
@@ -539,12 +654,14 @@ func (b *Board) blackKingMoves(pos Square) BitBoard {
 	moves := KingAtkTable[pos] & ^friendly
 	allOccupancy := friendly | enemy
 	if pos == E8 && b.CanBlackOO() {
-		if (allOccupancy&(1<<F8) == 0) && (allOccupancy&(1<<G8) == 0) && !b.isSqAttacked(G8, White) {
+		if (allOccupancy&(1<<F8) == 0) && (allOccupancy&(1<<G8) == 0) &&
+			!b.isSqAttacked(E8, White) && !b.isSqAttacked(F8, White) && !b.isSqAttacked(G8, White) {
 			moves = moves.Set(G8)
 		}
 	}
 	if pos == E8 && b.CanBlackOOO() {
-		if (allOccupancy&(1<<D8) == 0) && (allOccupancy&(1<<C8) == 0) && (allOccupancy&(1<<B8) == 0) && !b.isSqAttacked(C8, White) {
+		if (allOccupancy&(1<<D8) == 0) && (allOccupancy&(1<<C8) == 0) && (allOccupancy&(1<<B8) == 0) &&
+			!b.isSqAttacked(E8, White) && !b.isSqAttacked(D8, White) && !b.isSqAttacked(C8, White) {
 			moves = moves.Set(C8)
 		}
 	}
@@ -565,6 +682,9 @@ func (b *Board) isSqAttacked(sq Square, attackColor Color) bool {
 		} else if (GetRookMoves(sq, b.blackOccupancy()|b.whiteOccupancy()) & (b.bitBoards[Rw] | b.bitBoards[Qw])) > 0 {
 			slog.Debug("Square attacked by white Rooks", "square", sq.ToStr())
 			return true
+		} else if (KingAtkTable[sq] & b.bitBoards[Kw]) > 0 {
+			slog.Debug("Square attacked by white King", "square", sq.ToStr())
+			return true
 		} else {
 			return false
 		}
@@ -581,6 +701,9 @@ func (b *Board) isSqAttacked(sq Square, attackColor Color) bool {
 		} else if (GetRookMoves(sq, b.blackOccupancy()|b.whiteOccupancy()) & (b.bitBoards[Rb] | b.bitBoards[Qb])) > 0 {
 			slog.Debug("Square attacked by black Rooks", "square", sq.ToStr())
 			return true
+		} else if (KingAtkTable[sq] & b.bitBoards[Kb]) > 0 {
+			slog.Debug("Square attacked by black King", "square", sq.ToStr())
+			return true
 		} else {
 			return false
 		}
@@ -640,15 +763,12 @@ func (b *Board) getLegalMoves(sq Square) (MoveList, bool) {
 		if b.isMoveLegal(move) {
 			slog.Debug("Move legal", "move", move.ToStr())
 			if move.IsPromotion() {
-				// create all promotion moves
-				move.SetPromPiece(Knight)
-				move_list = append(move_list, move)
-				move.SetPromPiece(Rook)
-				move_list = append(move_list, move)
-				move.SetPromPiece(Bishop)
-				move_list = append(move_list, move)
-				move.SetPromPiece(Queen)
-				move_list = append(move_list, move)
+				// create all promotion moves, each from a fresh copy as SetPromPiece adds to the flags
+				for _, prom := range []promotedPiece{Knight, Bishop, Rook, Queen} {
+					prom_move := move
+					prom_move.SetPromPiece(prom)
+					move_list = append(move_list, prom_move)
+				}
 			} else {
 				move_list = append(move_list, move)
 			}
@@ -712,6 +832,11 @@ func (b *Board) isActiveSideInCheck() bool {
 	return b.isSqAttacked(sq, b.activeColor^1)
 }
 
+// GetAllLegalMoves returns all the legal moves of the active side.
+func (b *Board) GetAllLegalMoves() MoveList {
+	return b.getAllLegalMoves(b.activeColor)
+}
+
 func (b *Board) GetActiveColor() Color {
 	return b.activeColor
 }
@@ -0,0 +1,415 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DelayMode says how the delay of a time control stage is applied.
+type DelayMode uint8
+
+const (
+	// SimpleDelay lets the clock start running down only after the delay has passed.
+	SimpleDelay DelayMode = iota
+	// BronsteinDelay gives back the time used for a move, up to the delay.
+	BronsteinDelay
+)
+
+// TimeControlStage is one period of a time control.
+type TimeControlStage struct {
+	// Moves is the number of moves to be made in the stage, 0 for the rest of the game.
+	Moves     int
+	Time      time.Duration
+	Increment time.Duration
+	Delay     time.Duration
+	DelayMode DelayMode
+}
+
+// TimeControl is a sequence of stages. The time of a stage is added to the clock once
+// the moves of the previous stage have been made. The last stage is repeated if it has
+// a number of moves, e.g. 40/7200 gives two hours for every 40 moves.
+type TimeControl []TimeControlStage
+
+// SuddenDeath gives each side t for the whole game.
+func SuddenDeath(t time.Duration) TimeControl {
+	return TimeControl{{Time: t}}
+}
+
+// Fischer gives each side t for the whole game and increment after every move.
+func Fischer(t time.Duration, increment time.Duration) TimeControl {
+	return TimeControl{{Time: t, Increment: increment}}
+}
+
+// ParseTimeControl parses a time control in the format of the PGN TimeControl tag,
+// stages separated by colons with the times in seconds, e.g. 40/5400+30:1800+30.
+// A stage can have a delay of d seconds, given as dd for a simple delay and bd for
+// a Bronstein delay after the time and the increment, e.g. 300d5 or 300+2b3.
+func ParseTimeControl(s string) (TimeControl, error) {
+	var tc TimeControl
+	for _, field := range strings.Split(s, ":") {
+		stage, err := parseTimeControlStage(field)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid time control %s: %w", s, err)
+		}
+		tc = append(tc, stage)
+	}
+	for _, stage := range tc[:len(tc)-1] {
+		if stage.Moves == 0 {
+			return nil, fmt.Errorf("Invalid time control %s: Only the last stage can be sudden death.", s)
+		}
+	}
+	return tc, nil
+}
+
+func parseTimeControlStage(s string) (TimeControlStage, error) {
+	var stage TimeControlStage
+	if moves, rest, ok := strings.Cut(s, "/"); ok {
+		n, err := strconv.Atoi(moves)
+		if err != nil || n <= 0 {
+			return stage, fmt.Errorf("Invalid number of moves: %s", moves)
+		}
+		stage.Moves = n
+		s = rest
+	}
+	if i := strings.IndexAny(s, "db"); i >= 0 {
+		if s[i] == 'b' {
+			stage.DelayMode = BronsteinDelay
+		}
+		delay, err := parseSeconds(s[i+1:])
+		if err != nil {
+			return stage, err
+		}
+		stage.Delay = delay
+		s = s[:i]
+	}
+	base, increment, hasIncrement := strings.Cut(s, "+")
+	t, err := parseSeconds(base)
+	if err != nil {
+		return stage, err
+	}
+	if t == 0 {
+		return stage, errors.New("The time of a stage cannot be 0.")
+	}
+	stage.Time = t
+	if hasIncrement {
+		if stage.Increment, err = parseSeconds(increment); err != nil {
+			return stage, err
+		}
+	}
+	return stage, nil
+}
+
+func parseSeconds(s string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("Invalid number of seconds: %s", s)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// ToStr returns the time control in the format read by ParseTimeControl.
+func (tc TimeControl) ToStr() string {
+	seconds := func(d time.Duration) string {
+		return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+	}
+	stages := make([]string, len(tc))
+	for i, stage := range tc {
+		s := seconds(stage.Time)
+		if stage.Moves > 0 {
+			s = fmt.Sprintf("%d/%s", stage.Moves, s)
+		}
+		if stage.Increment > 0 {
+			s += "+" + seconds(stage.Increment)
+		}
+		if stage.Delay > 0 {
+			if stage.DelayMode == BronsteinDelay {
+				s += "b" + seconds(stage.Delay)
+			} else {
+				s += "d" + seconds(stage.Delay)
+			}
+		}
+		stages[i] = s
+	}
+	return strings.Join(stages, ":")
+}
+
+// ClockSource tells the time. Clocks use the system time unless they are given
+// another source, like a ManualClockSource to run them without waiting.
+type ClockSource interface {
+	Now() time.Time
+}
+
+type systemClockSource struct{}
+
+func (systemClockSource) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClockSource is a ClockSource whose time only moves when it is advanced.
+type ManualClockSource struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewManualClockSource(start time.Time) *ManualClockSource {
+	return &ManualClockSource{now: start}
+}
+
+func (s *ManualClockSource) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now
+}
+
+func (s *ManualClockSource) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = s.now.Add(d)
+}
+
+// clockMove is what a Clock needs to take a move back.
+type clockMove struct {
+	side Color
+	// remaining is the time the side had left after the move, before any time was added.
+	remaining  time.Duration
+	stage      int
+	stageMoves int
+}
+
+// Clock is a chess clock for both sides. Only the clock of the side to move runs.
+// It is safe for concurrent use.
+type Clock struct {
+	control TimeControl
+	source  ClockSource
+
+	mu sync.Mutex
+
+	remaining [2]time.Duration
+	// stage is the stage each side is in and stageMoves the moves it made in the stage.
+	stage      [2]int
+	stageMoves [2]int
+	history    []clockMove
+
+	active  Color
+	running bool
+	paused  bool
+	// turnStart is when the clock of the active side was last started and turnUsed
+	// the time it used in its turn before the clock was paused.
+	turnStart time.Time
+	turnUsed  time.Duration
+}
+
+// NewClock creates a stopped clock for tc. A nil source uses the system time.
+func NewClock(tc TimeControl, source ClockSource) *Clock {
+	if len(tc) == 0 {
+		panic("A time control needs at least one stage.")
+	}
+	if source == nil {
+		source = systemClockSource{}
+	}
+	return &Clock{
+		control:   tc,
+		source:    source,
+		remaining: [2]time.Duration{tc[0].Time, tc[0].Time},
+	}
+}
+
+func (c *Clock) Control() TimeControl {
+	return c.control
+}
+
+// Start starts the clock of side.
+func (c *Clock) Start(side Color) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.active = side
+	c.running = true
+	c.startTurn()
+}
+
+func (c *Clock) startTurn() {
+	c.turnStart = c.source.Now()
+	c.turnUsed = 0
+}
+
+// Stop stops the clock for good, e.g. when the game is over.
+func (c *Clock) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stop()
+}
+
+func (c *Clock) stop() {
+	if !c.running {
+		return
+	}
+	c.remaining[c.active] = c.remainingOf(c.active)
+	c.running = false
+}
+
+func (c *Clock) IsRunning() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.running
+}
+
+// Active returns the side whose clock is running.
+func (c *Clock) Active() Color {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.active
+}
+
+// Pause stops the clock of the active side until Resume is called.
+func (c *Clock) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.running || c.paused {
+		return
+	}
+	c.turnUsed = c.turnElapsed()
+	c.paused = true
+}
+
+func (c *Clock) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.running || !c.paused {
+		return
+	}
+	c.turnStart = c.source.Now()
+	c.paused = false
+}
+
+func (c *Clock) IsPaused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+// turnElapsed returns the time the active side has used in its turn.
+func (c *Clock) turnElapsed() time.Duration {
+	if c.paused {
+		return c.turnUsed
+	}
+	return c.turnUsed + c.source.Now().Sub(c.turnStart)
+}
+
+// charged returns the part of elapsed which is taken off the clock of the active side.
+func (c *Clock) charged(elapsed time.Duration) time.Duration {
+	stage := c.control[c.stage[c.active]]
+	if stage.DelayMode == SimpleDelay {
+		return max(elapsed-stage.Delay, 0)
+	}
+	return elapsed
+}
+
+// Remaining returns the time side has left, which is negative once its flag has fallen.
+func (c *Clock) Remaining(side Color) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.remainingOf(side)
+}
+
+func (c *Clock) remainingOf(side Color) time.Duration {
+	if !c.running || side != c.active {
+		return c.remaining[side]
+	}
+	return c.remaining[side] - c.charged(c.turnElapsed())
+}
+
+// Flagged returns the side whose time has run out.
+func (c *Clock) Flagged() (Color, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, side := range []Color{White, Black} {
+		if c.remainingOf(side) <= 0 {
+			return side, true
+		}
+	}
+	return White, false
+}
+
+// MovesToGo returns the number of moves side has to make until it gets more time,
+// 0 if the stage lasts for the rest of the game.
+func (c *Clock) MovesToGo(side Color) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.movesToGo(side)
+}
+
+func (c *Clock) movesToGo(side Color) int {
+	stage := c.control[c.stage[side]]
+	if stage.Moves == 0 {
+		return 0
+	}
+	return stage.Moves - c.stageMoves[side]
+}
+
+// Budget returns how long side should think about its next move, see TimeBudget.
+func (c *Clock) Budget(side Color) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stage := c.control[c.stage[side]]
+	return TimeBudget(c.remainingOf(side), stage.Increment+stage.Delay, c.movesToGo(side))
+}
+
+// Press ends the turn of the active side and starts the clock of its opponent.
+// It returns false without switching the clocks if the active side has run out of time.
+func (c *Clock) Press() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.running {
+		return false
+	}
+	side := c.active
+	elapsed := c.turnElapsed()
+	left := c.remaining[side] - c.charged(elapsed)
+	if left <= 0 {
+		c.stop()
+		return false
+	}
+	c.history = append(c.history, clockMove{side, left, c.stage[side], c.stageMoves[side]})
+
+	stage := c.control[c.stage[side]]
+	if stage.DelayMode == BronsteinDelay {
+		left += min(elapsed, stage.Delay)
+	}
+	left += stage.Increment
+	c.stageMoves[side]++
+	if stage.Moves > 0 && c.stageMoves[side] == stage.Moves {
+		c.stage[side] = min(c.stage[side]+1, len(c.control)-1)
+		c.stageMoves[side] = 0
+		left += c.control[c.stage[side]].Time
+	}
+	c.remaining[side] = left
+	c.active = 1 ^ side
+	c.paused = false
+	c.startTurn()
+	return true
+}
+
+// Takeback gives the turn back to the side which pressed the clock last. The time
+// used by the active side is kept off its clock, the other side gets back the time
+// it had when it moved but loses the time added for the move.
+func (c *Clock) Takeback() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.running || len(c.history) == 0 {
+		return false
+	}
+	c.remaining[c.active] = c.remainingOf(c.active)
+	last := c.history[len(c.history)-1]
+	c.history = c.history[:len(c.history)-1]
+	c.remaining[last.side] = last.remaining
+	c.stage[last.side] = last.stage
+	c.stageMoves[last.side] = last.stageMoves
+	c.active = last.side
+	c.paused = false
+	c.startTurn()
+	return true
+}
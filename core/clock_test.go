@@ -0,0 +1,239 @@
+package core
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestClock(t *testing.T, control string) (*Clock, *ManualClockSource) {
+	t.Helper()
+	tc, err := ParseTimeControl(control)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := NewManualClockSource(time.Unix(0, 0))
+	clock := NewClock(tc, source)
+	clock.Start(White)
+	return clock, source
+}
+
+// play lets the sides think for the given times in turn, pressing the clock after each.
+func play(t *testing.T, clock *Clock, source *ManualClockSource, thinks []time.Duration) {
+	t.Helper()
+	for i, think := range thinks {
+		source.Advance(think)
+		if !clock.Press() {
+			t.Fatalf("The clock could not be pressed after move %d.", i+1)
+		}
+	}
+}
+
+func TestClockTimeControls(t *testing.T) {
+	s := time.Second
+	tests := []struct {
+		name    string
+		control string
+		thinks  []time.Duration
+		want    [2]time.Duration
+		stage   [2]int
+	}{
+		{"sudden death", "300", []time.Duration{10 * s, 5 * s}, [2]time.Duration{290 * s, 295 * s}, [2]int{}},
+		{"fischer", "300+2", []time.Duration{10 * s, 5 * s, 1 * s}, [2]time.Duration{293 * s, 297 * s}, [2]int{}},
+		{"simple delay within the delay", "300d5", []time.Duration{3 * s, 5 * s}, [2]time.Duration{300 * s, 300 * s}, [2]int{}},
+		{"simple delay", "300d5", []time.Duration{8 * s, 20 * s}, [2]time.Duration{297 * s, 285 * s}, [2]int{}},
+		{"bronstein delay within the delay", "300b5", []time.Duration{3 * s, 5 * s}, [2]time.Duration{300 * s, 300 * s}, [2]int{}},
+		{"bronstein delay", "300b5", []time.Duration{8 * s, 20 * s}, [2]time.Duration{297 * s, 285 * s}, [2]int{}},
+		{"increment and delay", "300+2d5", []time.Duration{8 * s}, [2]time.Duration{299 * s, 300 * s}, [2]int{}},
+		{
+			"multi-stage rollover", "40/5400+30:1800+30",
+			slices.Repeat([]time.Duration{60 * s}, 80),
+			[2]time.Duration{6000 * s, 6000 * s}, [2]int{1, 1},
+		},
+		{
+			"multi-stage before the rollover", "40/5400+30:1800+30",
+			slices.Repeat([]time.Duration{60 * s}, 79),
+			[2]time.Duration{6000 * s, 4230 * s}, [2]int{1, 0},
+		},
+		{
+			"repeated last stage", "2/60",
+			slices.Repeat([]time.Duration{10 * s}, 8),
+			[2]time.Duration{140 * s, 140 * s}, [2]int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock, source := newTestClock(t, tt.control)
+			play(t, clock, source, tt.thinks)
+			for _, side := range []Color{White, Black} {
+				if got := clock.Remaining(side); got != tt.want[side] {
+					t.Errorf("%s has %s left, want %s.", side.ToStr(), got, tt.want[side])
+				}
+				if clock.stage[side] != tt.stage[side] {
+					t.Errorf("%s is in stage %d, want %d.", side.ToStr(), clock.stage[side], tt.stage[side])
+				}
+			}
+		})
+	}
+}
+
+func TestClockRunningSide(t *testing.T) {
+	clock, source := newTestClock(t, "60d5")
+	source.Advance(7 * time.Second)
+	if got := clock.Remaining(White); got != 58*time.Second {
+		t.Errorf("White has %s left while thinking, want 58s.", got)
+	}
+	if got := clock.Remaining(Black); got != 60*time.Second {
+		t.Errorf("The clock of Black ran while White was thinking, it shows %s.", got)
+	}
+	if got := clock.MovesToGo(White); got != 0 {
+		t.Errorf("MovesToGo is %d in sudden death, want 0.", got)
+	}
+}
+
+func TestClockTakeback(t *testing.T) {
+	s := time.Second
+	clock, source := newTestClock(t, "2/60+1:30")
+	play(t, clock, source, []time.Duration{10 * s, 5 * s, 10 * s})
+	// White has made both moves of the first stage and got the 30 seconds of the second
+	if got := clock.Remaining(White); got != 72*s {
+		t.Fatalf("White has %s left, want 72s.", got)
+	}
+
+	source.Advance(4 * s)
+	if !clock.Takeback() {
+		t.Fatal("Takeback failed.")
+	}
+	if clock.Active() != White {
+		t.Errorf("The clock of %s runs after the takeback, want White.", clock.Active().ToStr())
+	}
+	// White gets back the time it had before the increment and the new stage
+	if got := clock.Remaining(White); got != 41*s {
+		t.Errorf("White has %s left after the takeback, want 41s.", got)
+	}
+	// Black keeps the time it used before the takeback off its clock
+	if got := clock.Remaining(Black); got != 52*s {
+		t.Errorf("Black has %s left after the takeback, want 52s.", got)
+	}
+	if clock.stage[White] != 0 || clock.stageMoves[White] != 1 {
+		t.Errorf("White is in stage %d after %d moves, want stage 0 after 1 move.", clock.stage[White], clock.stageMoves[White])
+	}
+
+	// the move is made again and the stage rolls over once more
+	play(t, clock, source, []time.Duration{1 * s})
+	if got := clock.Remaining(White); got != 71*s {
+		t.Errorf("White has %s left after moving again, want 71s.", got)
+	}
+
+	for clock.Takeback() {
+	}
+	if got := clock.Remaining(White); got != 50*s {
+		t.Errorf("White has %s left after taking back all moves, want 50s.", got)
+	}
+	if clock.Takeback() {
+		t.Error("Takeback succeeded without moves to take back.")
+	}
+}
+
+func TestClockPause(t *testing.T) {
+	s := time.Second
+	clock, source := newTestClock(t, "60")
+	source.Advance(10 * s)
+	clock.Pause()
+	if !clock.IsPaused() {
+		t.Fatal("The clock is not paused.")
+	}
+	source.Advance(100 * s)
+	if got := clock.Remaining(White); got != 50*s {
+		t.Errorf("White has %s left while paused, want 50s.", got)
+	}
+	if _, flagged := clock.Flagged(); flagged {
+		t.Error("A flag fell while the clock was paused.")
+	}
+	clock.Resume()
+	source.Advance(5 * s)
+	if got := clock.Remaining(White); got != 45*s {
+		t.Errorf("White has %s left after resuming, want 45s.", got)
+	}
+
+	clock.Pause()
+	if !clock.Press() {
+		t.Fatal("The clock could not be pressed while paused.")
+	}
+	if clock.IsPaused() {
+		t.Error("Pressing the clock did not resume it.")
+	}
+	source.Advance(3 * s)
+	if got := clock.Remaining(Black); got != 57*s {
+		t.Errorf("Black has %s left, want 57s.", got)
+	}
+}
+
+func TestClockFlagFall(t *testing.T) {
+	s := time.Second
+	clock, source := newTestClock(t, "10+5")
+	play(t, clock, source, []time.Duration{9 * s})
+	source.Advance(10 * s)
+	side, flagged := clock.Flagged()
+	if !flagged || side != Black {
+		t.Fatalf("Flagged returned %s, %t, want Black, true.", side.ToStr(), flagged)
+	}
+	if clock.Press() {
+		t.Error("Black could press the clock after its flag fell.")
+	}
+	if clock.IsRunning() {
+		t.Error("The clock still runs after the flag fell.")
+	}
+	if got := clock.Remaining(Black); got != 0 {
+		t.Errorf("Black has %s left, want 0s.", got)
+	}
+	if got := clock.Remaining(White); got != 6*s {
+		t.Errorf("White has %s left, want 6s.", got)
+	}
+}
+
+func TestParseTimeControl(t *testing.T) {
+	s := time.Second
+	tests := []struct {
+		control string
+		want    TimeControl
+	}{
+		{"300", TimeControl{{Time: 300 * s}}},
+		{"180+2", TimeControl{{Time: 180 * s, Increment: 2 * s}}},
+		{"0.5+0.1", TimeControl{{Time: 500 * time.Millisecond, Increment: 100 * time.Millisecond}}},
+		{"300d5", TimeControl{{Time: 300 * s, Delay: 5 * s, DelayMode: SimpleDelay}}},
+		{"300+2b3", TimeControl{{Time: 300 * s, Increment: 2 * s, Delay: 3 * s, DelayMode: BronsteinDelay}}},
+		{"40/7200", TimeControl{{Moves: 40, Time: 7200 * s}}},
+		{"40/5400+30:1800+30", TimeControl{
+			{Moves: 40, Time: 5400 * s, Increment: 30 * s},
+			{Time: 1800 * s, Increment: 30 * s},
+		}},
+		{"40/7200:20/3600:900+30", TimeControl{
+			{Moves: 40, Time: 7200 * s},
+			{Moves: 20, Time: 3600 * s},
+			{Time: 900 * s, Increment: 30 * s},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.control, func(t *testing.T) {
+			tc, err := ParseTimeControl(tt.control)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(tc, tt.want) {
+				t.Errorf("ParseTimeControl returned %+v, want %+v.", tc, tt.want)
+			}
+			if got := tc.ToStr(); got != tt.control {
+				t.Errorf("ToStr returned %s, want %s.", got, tt.control)
+			}
+		})
+	}
+}
+
+func TestParseTimeControlErrors(t *testing.T) {
+	for _, control := range []string{"", "abc", "0", "-5", "0/300", "x/300", "300+", "300+-1", "300dx", "300:40/60", "40/"} {
+		if tc, err := ParseTimeControl(control); err == nil {
+			t.Errorf("ParseTimeControl(%q) returned %+v, want an error.", control, tc)
+		}
+	}
+}
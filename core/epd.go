@@ -0,0 +1,138 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EPD is a position of an Extended Position Description record together with its operations.
+type EPD struct {
+	Board Board
+	// Operations holds the operands of every operation by its opcode, including
+	// the ones which are not interpreted below.
+	Operations map[string][]string
+	// ID is the id operation.
+	ID string
+	// Comment is the c0 operation.
+	Comment string
+	// BestMoves are the moves of the bm operation.
+	BestMoves []Move
+	// AvoidMoves are the moves of the am operation.
+	AvoidMoves []Move
+	// DirectMate is the number of moves of the dm operation, 0 if there is none.
+	DirectMate int
+}
+
+// ParseEPD parses a single EPD record. It consists of the first four fields of a FEN
+// followed by operations, each an opcode with its operands terminated by a semicolon.
+// The moves of bm and am are given in SAN.
+func ParseEPD(line string) (EPD, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return EPD{}, errors.New("An EPD record needs at least four fields.")
+	}
+	epd := EPD{Operations: make(map[string][]string)}
+	rest := strings.TrimSpace(line)
+	for range 4 {
+		rest = strings.TrimSpace(rest)
+		rest = rest[strings.IndexAny(rest+" ", " \t"):]
+	}
+	ops, err := parseEPDOperations(rest)
+	if err != nil {
+		return EPD{}, err
+	}
+	for _, op := range ops {
+		epd.Operations[op[0]] = op[1:]
+	}
+
+	halfMoveClock, fullMoveNumber := "0", "1"
+	if v := epd.Operations["hmvc"]; len(v) > 0 {
+		halfMoveClock = v[0]
+	}
+	if v := epd.Operations["fmvn"]; len(v) > 0 {
+		fullMoveNumber = v[0]
+	}
+	fen := strings.Join(append(fields[:4:4], halfMoveClock, fullMoveNumber), " ")
+	if epd.Board, err = BoardFromFen(fen); err != nil {
+		return EPD{}, err
+	}
+
+	if v := epd.Operations["id"]; len(v) > 0 {
+		epd.ID = strings.Join(v, " ")
+	}
+	if v := epd.Operations["c0"]; len(v) > 0 {
+		epd.Comment = strings.Join(v, " ")
+	}
+	if epd.BestMoves, err = epd.parseMoves("bm"); err != nil {
+		return EPD{}, err
+	}
+	if epd.AvoidMoves, err = epd.parseMoves("am"); err != nil {
+		return EPD{}, err
+	}
+	if v := epd.Operations["dm"]; len(v) > 0 {
+		if epd.DirectMate, err = strconv.Atoi(v[0]); err != nil {
+			return EPD{}, fmt.Errorf("Invalid dm operand: %s", v[0])
+		}
+	}
+	return epd, nil
+}
+
+func (epd *EPD) parseMoves(opcode string) ([]Move, error) {
+	var moves []Move
+	for _, san := range epd.Operations[opcode] {
+		move, err := epd.Board.ParseSAN(san)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s operand: %w", opcode, err)
+		}
+		moves = append(moves, move)
+	}
+	return moves, nil
+}
+
+// parseEPDOperations splits the operations of an EPD record into the opcode and the operands
+// of each operation. Quoted operands may contain spaces and semicolons.
+func parseEPDOperations(s string) ([][]string, error) {
+	var ops [][]string
+	var op []string
+	var token strings.Builder
+	inToken, quoted := false, false
+	endToken := func() {
+		if inToken {
+			op = append(op, token.String())
+			token.Reset()
+			inToken = false
+		}
+	}
+	for _, c := range s {
+		switch {
+		case quoted && c == '"':
+			quoted = false
+		case quoted:
+			token.WriteRune(c)
+		case c == '"':
+			quoted, inToken = true, true
+		case c == ';':
+			endToken()
+			if len(op) > 0 {
+				ops = append(ops, op)
+			}
+			op = nil
+		case c == ' ' || c == '\t':
+			endToken()
+		default:
+			token.WriteRune(c)
+			inToken = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("Unterminated string in EPD operation.")
+	}
+	endToken()
+	if len(op) > 0 {
+		// the last operation is missing its semicolon
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
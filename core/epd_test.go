@@ -0,0 +1,26 @@
+package core
+
+import "testing"
+
+func TestParseEPDPromotions(t *testing.T) {
+	epd, err := ParseEPD(`1r5k/P3P3/8/8/8/8/8/K7 w - - bm axb8=Q+ e8=B; am a8=Q; id "promotions";`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if epd.ID != "promotions" {
+		t.Errorf("The id is %q, want \"promotions\".", epd.ID)
+	}
+	want := func(moves []Move, strs ...string) {
+		t.Helper()
+		if len(moves) != len(strs) {
+			t.Fatalf("%d moves were parsed, want %d.", len(moves), len(strs))
+		}
+		for i, m := range moves {
+			if m.ToStr() != strs[i] {
+				t.Errorf("Move %d is %s, want %s.", i, m.ToStr(), strs[i])
+			}
+		}
+	}
+	want(epd.BestMoves, "a7b8q", "e7e8b")
+	want(epd.AvoidMoves, "a7a8q")
+}
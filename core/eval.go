@@ -4,7 +4,12 @@ const MinScore int32 = -10000000
 const MaxScore int32 = 10000000
 const MatingScore int32 = -9999999
 
-var PieceScore = [...]int32{300, 350, 500, 1000, 10000, 100, -300, -350, -500, -1000, -10000, 100}
+// isMateScore reports whether score is a forced mate for either side.
+func isMateScore(score int32) bool {
+	return score <= MatingScore+maxPly || score >= -MatingScore-maxPly
+}
+
+var PieceScore = [...]int32{300, 350, 500, 1000, 10000, 100, -300, -350, -500, -1000, -10000, -100}
 
 var PawnPosScore = [...]int32{
 	0, 0, 0, 0, 0, 0, 0, 0,
@@ -0,0 +1,239 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is something which happened in a ChessGame, one of the *Event types below.
+type Event interface {
+	event()
+}
+
+// MoveEvent is sent after a move has been made, including redone moves.
+type MoveEvent struct {
+	Move Move
+	SAN  string
+	Side Color
+	// Board is the position after the move.
+	Board Board
+	Ply   int
+}
+
+// UndoEvent is sent after a move has been taken back.
+type UndoEvent struct {
+	Move Move
+	// Board is the position the move had been made in.
+	Board Board
+	Ply   int
+}
+
+// CheckEvent is sent after a move which gives check, after its MoveEvent.
+type CheckEvent struct {
+	// Side is the side in check.
+	Side Color
+}
+
+// GameOverEvent is sent when the game ends.
+type GameOverEvent struct {
+	Result GameResult
+}
+
+// DrawOfferEvent is sent when a side offers a draw.
+type DrawOfferEvent struct {
+	By Color
+}
+
+// ClockTickEvent is sent every clockTickInterval while the clock runs.
+type ClockTickEvent struct {
+	Active    Color
+	Remaining [2]time.Duration
+}
+
+// SearchProgressEvent is sent when the AI of Side has completed an iteration of its search.
+type SearchProgressEvent struct {
+	Side   Color
+	Result SearchResult
+}
+
+func (MoveEvent) event()           {}
+func (UndoEvent) event()           {}
+func (CheckEvent) event()          {}
+func (GameOverEvent) event()       {}
+func (DrawOfferEvent) event()      {}
+func (ClockTickEvent) event()      {}
+func (SearchProgressEvent) event() {}
+
+// Listener receives the events of a game.
+type Listener func(Event)
+
+// clockTickInterval is how often ClockTickEvents are sent.
+const clockTickInterval = 100 * time.Millisecond
+
+// eventBus delivers events to the listeners of a game. Events are queued, so sending
+// them never blocks, and delivered in order on a goroutine of the bus. Listeners are
+// never called concurrently, but they must not block for long as they hold up the
+// events of the other listeners.
+type eventBus struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	listeners map[int]Listener
+	nextID    int
+	queue     []Event
+	// sent and delivered count the events, so that flush can wait for them.
+	sent      uint64
+	delivered uint64
+	running   bool
+	// stopTicker stops the goroutine sending ClockTickEvents, it is nil when there is none.
+	stopTicker chan struct{}
+}
+
+func newEventBus() *eventBus {
+	b := &eventBus{listeners: map[int]Listener{}}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// subscribe adds l and returns the function which removes it again.
+// tick is called every clockTickInterval while there are listeners, it may be nil.
+func (b *eventBus) subscribe(l Listener, tick func() (Event, bool)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextID
+	b.nextID++
+	b.listeners[id] = l
+	if !b.running {
+		b.running = true
+		go b.deliver()
+	}
+	if tick != nil && b.stopTicker == nil {
+		b.stopTicker = make(chan struct{})
+		go b.runTicker(tick, b.stopTicker)
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.listeners, id)
+			if len(b.listeners) == 0 {
+				if b.stopTicker != nil {
+					close(b.stopTicker)
+					b.stopTicker = nil
+				}
+				b.cond.Broadcast()
+			}
+		})
+	}
+}
+
+// emit queues e for the listeners. Events sent while there are no listeners are dropped.
+func (b *eventBus) emit(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.listeners) == 0 {
+		return
+	}
+	b.queue = append(b.queue, e)
+	b.sent++
+	b.cond.Broadcast()
+}
+
+// deliver runs until the last listener is removed, passing the queued events to the listeners.
+func (b *eventBus) deliver() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		for len(b.queue) == 0 && len(b.listeners) > 0 {
+			b.cond.Wait()
+		}
+		if len(b.listeners) == 0 {
+			b.delivered += uint64(len(b.queue))
+			b.queue = nil
+			b.running = false
+			b.cond.Broadcast()
+			return
+		}
+		e := b.queue[0]
+		b.queue = b.queue[1:]
+		listeners := make([]Listener, 0, len(b.listeners))
+		for id := 0; id < b.nextID; id++ {
+			if l, ok := b.listeners[id]; ok {
+				listeners = append(listeners, l)
+			}
+		}
+		b.mu.Unlock()
+		for _, l := range listeners {
+			l(e)
+		}
+		b.mu.Lock()
+		b.delivered++
+		b.cond.Broadcast()
+	}
+}
+
+// flush waits until the events sent so far have been delivered.
+func (b *eventBus) flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sent := b.sent
+	for b.delivered < sent {
+		b.cond.Wait()
+	}
+}
+
+func (b *eventBus) runTicker(tick func() (Event, bool), stop chan struct{}) {
+	ticker := time.NewTicker(clockTickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if e, ok := tick(); ok {
+				b.emit(e)
+			}
+		}
+	}
+}
+
+// Subscribe makes l receive the events of the game until the returned function is called.
+// l is called on a goroutine of the game, one event at a time and in the order they happened.
+func (g *ChessGame) Subscribe(l Listener) (unsubscribe func()) {
+	// the clock is looked up on every tick as loading a saved game replaces it
+	tick := func() (Event, bool) {
+		clock := g.Clock()
+		if clock == nil || !clock.IsRunning() || clock.IsPaused() {
+			return nil, false
+		}
+		return ClockTickEvent{clock.Active(), [2]time.Duration{clock.Remaining(White), clock.Remaining(Black)}}, true
+	}
+	return g.events.subscribe(l, tick)
+}
+
+// SubscribeChan returns a channel receiving the events of the game until the returned
+// function is called. The channel is not closed then. A reader which falls more than
+// buffer events behind holds up the other listeners, but never the game.
+func (g *ChessGame) SubscribeChan(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+	done := make(chan struct{})
+	unsubscribe := g.Subscribe(func(e Event) {
+		select {
+		case ch <- e:
+		case <-done:
+		}
+	})
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			close(done)
+			unsubscribe()
+		})
+	}
+}
+
+// FlushEvents blocks until the events which have happened so far have been delivered.
+// It must not be called from a listener.
+func (g *ChessGame) FlushEvents() {
+	g.events.flush()
+}
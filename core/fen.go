@@ -9,19 +9,28 @@ import (
 	u "unicode"
 )
 
+const StartFen = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
 func BoardFromFen(fen string) (Board, error) {
 	var board Board
 	board.castlingFlags = 0b1111
 	board.activeColor = White
 	board.halfMoveClock = 0
-	board.fullMoveClock = 0
+	board.fullMoveClock = 1
 	board.epTarget = epTarget{false, 0}
 
 	fenParts := s.Split(fen, " ")
 	piecesPart := fenParts[0]
-	for i, row := range s.Split(piecesPart, "/") {
+	rows := s.Split(piecesPart, "/")
+	if len(rows) != 8 {
+		return board, errors.New("Error while parsing fen.")
+	}
+	for i, row := range rows {
 		j := 0
 		for _, c := range row {
+			if j >= 8 {
+				return board, errors.New("Error while parsing fen.")
+			}
 			sq := Square((7-i)*8 + j)
 			if u.IsDigit(c) {
 				j += int(c - '0')
@@ -34,6 +43,9 @@ func BoardFromFen(fen string) (Board, error) {
 				j += 1
 			}
 		}
+		if j != 8 {
+			return board, errors.New("Error while parsing fen.")
+		}
 	}
 
 	if len(fenParts) > 1 {
@@ -50,6 +62,8 @@ func BoardFromFen(fen string) (Board, error) {
 
 	if len(fenParts) > 2 {
 		castlingRights := fenParts[2]
+		// only the rights listed in the fen are kept
+		board.castlingFlags = 0
 		if castlingRights != "-" {
 			for _, c := range castlingRights {
 				switch c {
@@ -84,7 +98,7 @@ func BoardFromFen(fen string) (Board, error) {
 		if fenParts[4] != "-" {
 			halfMoveClock, err := strconv.Atoi(fenParts[4])
 			if err != nil {
-				return board, errors.New(fmt.Sprintf("Invalid halfMoveclock number: %s", halfMoveClock))
+				return board, errors.New(fmt.Sprintf("Invalid halfMoveclock number: %s", fenParts[4]))
 			}
 			board.halfMoveClock = uint(halfMoveClock)
 		}
@@ -94,7 +108,7 @@ func BoardFromFen(fen string) (Board, error) {
 		if fenParts[5] != "-" {
 			fullMoveclock, err := strconv.Atoi(fenParts[5])
 			if err != nil {
-				return board, errors.New(fmt.Sprintf("Invalid fullMoveclock number: %s", fullMoveclock))
+				return board, errors.New(fmt.Sprintf("Invalid fullMoveclock number: %s", fenParts[5]))
 			}
 			board.fullMoveClock = uint(fullMoveclock)
 		}
@@ -103,3 +117,75 @@ func BoardFromFen(fen string) (Board, error) {
 	board.hash = board.calculateHash()
 	return board, nil
 }
+
+// ToFen returns the Forsyth-Edwards Notation of the board.
+func (b *Board) ToFen() string {
+	var buf s.Builder
+	for y := 7; y >= 0; y-- {
+		empty := 0
+		for x := range 8 {
+			piece, occupied := b.GetAtSq(SquareFromXY(x, y))
+			if !occupied {
+				empty += 1
+				continue
+			}
+			if empty > 0 {
+				buf.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			buf.WriteRune(piece.Char())
+		}
+		if empty > 0 {
+			buf.WriteString(strconv.Itoa(empty))
+		}
+		if y > 0 {
+			buf.WriteByte('/')
+		}
+	}
+
+	if b.activeColor == White {
+		buf.WriteString(" w ")
+	} else {
+		buf.WriteString(" b ")
+	}
+
+	castling := ""
+	if b.CanWhiteOO() {
+		castling += "K"
+	}
+	if b.CanWhiteOOO() {
+		castling += "Q"
+	}
+	if b.CanBlackOO() {
+		castling += "k"
+	}
+	if b.CanBlackOOO() {
+		castling += "q"
+	}
+	if castling == "" {
+		castling = "-"
+	}
+	buf.WriteString(castling)
+
+	if sq, exists := b.epTarget.get(); exists {
+		buf.WriteString(" " + sq.ToStr())
+	} else {
+		buf.WriteString(" -")
+	}
+	buf.WriteString(fmt.Sprintf(" %d %d", b.halfMoveClock, b.fullMoveClock))
+	return buf.String()
+}
+
+// MarshalText writes the board as its FEN.
+func (b Board) MarshalText() ([]byte, error) {
+	return []byte(b.ToFen()), nil
+}
+
+func (b *Board) UnmarshalText(text []byte) error {
+	board, err := BoardFromFen(string(text))
+	if err != nil {
+		return err
+	}
+	*b = board
+	return nil
+}
@@ -1,46 +1,259 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
-
-	"github.com/ParthPant/gochess/util"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
 )
 
+// ChessGame is safe for concurrent use. Its methods are serialized, so a front-end sees the
+// game either before or after a move but never in between. Snapshot returns the position.
 type ChessGame struct {
-	Ai         AI
-	HumanColor Color
-	Board      Board
-	history    util.Stack[Board]
+	// mu guards all the fields below. The exported methods lock it, the unexported ones
+	// expect it to be held.
+	mu    sync.Mutex
+	board Board
+	// players are indexed by Color.
+	players [2]Player
+	history MoveHistory
+	// tree has the moves of history along with the variations and annotations added to them.
+	tree       *GameTree
+	lastSearch *SearchResult
+	aiSearch   *SearchHandle
+	// ponder searches the reply to ponderMove while the opponent of the AI is thinking.
+	ponderEnabled bool
+	ponder        *SearchHandle
+	ponderMove    Move
+	// clock is nil for games without a time control.
+	clock  *Clock
+	result GameResult
+	// drawOfferBy is the side which offered a draw if drawOffered is set.
+	drawOfferBy Color
+	drawOffered bool
+	// events has its own lock, so that listeners can call the game.
+	events *eventBus
 }
 
-func NewGame(humanColor Color) ChessGame {
-	board, err := BoardFromFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR")
-	if err != nil {
-		panic("Error while constructing default fen board.")
+// GameOption configures a ChessGame created by NewGame.
+type GameOption func(*gameConfig)
+
+type gameConfig struct {
+	ai         AI
+	humanColor *Color
+	players    [2]Player
+	// start is the position the game starts from, the one of fen if it is nil.
+	// moves are played from it before the game begins.
+	start       *Board
+	fen         string
+	moves       []string
+	variant     Variant
+	strength    Strength
+	timeControl TimeControl
+	clockSource ClockSource
+}
+
+// Variant is the set of rules a game is played by.
+type Variant uint8
+
+const (
+	Standard Variant = iota
+)
+
+func (v Variant) ToStr() string {
+	switch v {
+	case Standard:
+		return "Standard"
+	default:
+		return fmt.Sprintf("Variant %d", v)
+	}
+}
+
+// WithFEN starts the game from the position of fen instead of the initial position.
+func WithFEN(fen string) GameOption {
+	return func(c *gameConfig) {
+		c.fen = fen
+		c.start = nil
+	}
+}
+
+// WithPosition starts the game from board instead of the initial position.
+func WithPosition(board Board) GameOption {
+	return func(c *gameConfig) {
+		c.start = &board
+	}
+}
+
+// WithMoves plays moves, in UCI or SAN notation, from the starting position before the
+// game begins. They are part of its history, so they can be undone and count for repetitions.
+func WithMoves(moves ...string) GameOption {
+	return func(c *gameConfig) {
+		c.moves = append(c.moves, moves...)
+	}
+}
+
+// WithHumanColor lets the human play color against the AI, instead of the side to move.
+func WithHumanColor(color Color) GameOption {
+	return func(c *gameConfig) {
+		c.humanColor = &color
+	}
+}
+
+// WithVariant plays the game by the rules of variant. Only Standard chess is supported so far.
+func WithVariant(variant Variant) GameOption {
+	return func(c *gameConfig) {
+		c.variant = variant
+	}
+}
+
+// WithAI makes the game use ai instead of a NegaMaxAI.
+func WithAI(ai AI) GameOption {
+	return func(c *gameConfig) {
+		c.ai = ai
+	}
+}
+
+// WithPlayers lets white and black play the game, instead of a human against an AI.
+func WithPlayers(white Player, black Player) GameOption {
+	return func(c *gameConfig) {
+		c.players = [2]Player{white, black}
+	}
+}
+
+// WithTimeControl plays the game with a clock. The clock of White starts right away.
+func WithTimeControl(tc TimeControl) GameOption {
+	return func(c *gameConfig) {
+		c.timeControl = tc
+	}
+}
+
+// WithClockSource makes the clock of the game tell the time with source instead of the system time.
+func WithClockSource(source ClockSource) GameOption {
+	return func(c *gameConfig) {
+		c.clockSource = source
+	}
+}
+
+// WithStrength limits the playing strength of the AI.
+func WithStrength(strength Strength) GameOption {
+	return func(c *gameConfig) {
+		c.strength = strength
+	}
+}
+
+// WithSkillLevel limits the AI to a skill level between 0 and MaxSkillLevel.
+func WithSkillLevel(level int) GameOption {
+	return WithStrength(NewStrength(level))
+}
+
+// WithElo limits the AI to play at about the given Elo rating.
+func WithElo(elo int) GameOption {
+	return WithStrength(StrengthFromElo(elo))
+}
+
+// NewGame creates a game from the initial position, or the one given by WithFEN or
+// WithPosition, which has to be legal. Unless WithPlayers is used a human plays the side
+// to move, or the side given by WithHumanColor, against an AI.
+func NewGame(opts ...GameOption) (*ChessGame, error) {
+	config := gameConfig{
+		fen:      StartFen,
+		strength: FullStrength(),
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.variant != Standard {
+		return nil, fmt.Errorf("Unsupported variant: %s", config.variant.ToStr())
+	}
+
+	var board Board
+	if config.start != nil {
+		board = *config.start
+	} else {
+		var err error
+		if board, err = BoardFromFen(config.fen); err != nil {
+			return nil, err
+		}
+	}
+	if err := board.Validate(); err != nil {
+		return nil, err
 	}
 	if board.hash != board.calculateHash() {
 		panic("Error: Zobrist has not set while construction.")
 	}
+	history := NewMoveHistory(board)
+	for _, s := range config.moves {
+		m, err := board.ParseMove(s)
+		if err != nil {
+			if m, err = board.ParseSAN(s); err != nil {
+				return nil, fmt.Errorf("Invalid move: %s", s)
+			}
+		}
+		history.Push(m, board)
+		board, _ = board.MakeMove(m)
+	}
+	humanColor := board.activeColor
+	if config.humanColor != nil {
+		humanColor = *config.humanColor
+	}
 
-	ai := NewNegaMaxAI()
-	return ChessGame{
-		&ai,
-		humanColor,
-		board,
-		util.NewStack[Board](),
+	players := config.players
+	if players[White] == nil || players[Black] == nil {
+		if config.ai == nil {
+			ai := NewNegaMaxAI()
+			ai.Threads = runtime.NumCPU()
+			config.ai = &ai
+		}
+		players[humanColor] = NewHumanPlayer("")
+		players[1^humanColor] = NewAIPlayer("", config.ai)
+	}
+	for _, player := range players {
+		if ai, ok := player.(*AIPlayer); ok {
+			if limiter, ok := ai.AI.(StrengthLimiter); ok {
+				limiter.SetStrength(config.strength)
+			}
+		}
+	}
+	g := &ChessGame{
+		board:   board,
+		players: players,
+		history: history,
+		events:  newEventBus(),
+	}
+	g.tree = newGameTreeFromHistory(&g.history)
+	if len(config.timeControl) > 0 {
+		g.clock = NewClock(config.timeControl, config.clockSource)
+		g.clock.Start(board.activeColor)
 	}
+	return g, nil
+}
+
+// Snapshot returns the current position. The board is a copy, so it stays consistent
+// while the game goes on.
+func (g *ChessGame) Snapshot() Board {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.board
 }
 
 // MakeMove will make a new move in the Game.
 // promPiece will be used only if the move is a promotion.
 func (g *ChessGame) MakeMove(from Square, to Square, promPiece promotedPiece) (Move, bool) {
-	legal_moves := g.GetLegalPieceMovesBB(from)
-	if !legal_moves.IsSet(to) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.isHumanTurn() {
+		slog.Info("It is not the turn of a human.", "player", g.playerToMove().Name())
+		return Move{}, false
+	}
+	legal_moves, _ := g.board.getLegalMoves(from)
+	if !legal_moves.ToBB().IsSet(to) {
 		slog.Info("Move is Illegal", "from", from.ToStr(), "to", to.ToStr())
 		return Move{}, false
 	}
-	move, ok := g.Board.inferMove(from, to)
+	move, ok := g.board.inferMove(from, to)
 	if !ok {
 		slog.Info("Unable to infer move.", "from", from.ToStr(), "to", from.ToStr())
 		return Move{}, false
@@ -48,27 +261,66 @@ func (g *ChessGame) MakeMove(from Square, to Square, promPiece promotedPiece) (M
 	if move.IsPromotion() {
 		move.SetPromPiece(promPiece)
 	}
-	return move, g.makeMoveImpl(move)
+	if !g.makeMoveImpl(move) {
+		return move, false
+	}
+	g.resolvePonder(move)
+	return move, true
 }
 
-// Implementation of MakeMove. It will also make a new entry in the history
+// Implementation of MakeMove. It will also make a new entry in the history.
+// Making the move which would be redone keeps the rest of the undone moves.
 func (g *ChessGame) makeMoveImpl(m Move) bool {
 	slog.Debug("Making Move:", "move", m.ToStr())
+	if g.isGameOver() {
+		slog.Info("The game is over.", "result", g.gameResult().ToStr())
+		return false
+	}
+	if g.isClockPaused() {
+		slog.Info("The clock is paused.")
+		return false
+	}
 	// Move is first made on a copy of the board.
-	board_copy, valid := g.Board.makeMove(m)
+	board_copy, valid := g.board.makeMove(m)
 	if valid {
+		if g.clock != nil && !g.clock.Press() {
+			g.flagFell()
+			return false
+		}
 		slog.Info("Valid Move.", "move", m.ToStr())
+		for _, player := range g.players {
+			if remote, ok := player.(*RemotePlayer); ok && remote != g.playerToMove() {
+				remote.send(m)
+			}
+		}
+		if g.drawOffered && g.drawOfferBy != g.board.activeColor {
+			// moving declines the draw offered by the opponent
+			g.drawOffered = false
+		}
 		// if the move is valid the board is replaced with it's copy
-		g.history.Push(g.Board)
-		g.Board = board_copy
+		side := g.board.activeColor
+		g.history.Push(m, g.board)
+		g.tree.gameMove(m)
+		g.board = board_copy
+		g.events.emit(MoveEvent{m, g.history.entries[g.history.ply-1].SAN, side, g.board, g.history.ply})
+		if g.board.isActiveSideInCheck() {
+			g.events.emit(CheckEvent{g.board.activeColor})
+		}
 
-		calculated_hash := g.Board.calculateHash()
+		calculated_hash := g.board.calculateHash()
 		slog.Debug("Board Hash",
-			slog.String("hash", fmt.Sprintf("0x%x", g.Board.hash)),
+			slog.String("hash", fmt.Sprintf("0x%x", g.board.hash)),
 			slog.String("calculated", fmt.Sprintf("0x%x", calculated_hash)))
-		if g.Board.hash != calculated_hash {
+		if g.board.hash != calculated_hash {
 			panic("Error: Hashes do not match")
 		}
+		if result := boardResult(&g.board); result.IsOver() {
+			slog.Info("Game over.", "result", result.ToStr())
+			if g.clock != nil {
+				g.clock.Stop()
+			}
+			g.events.emit(GameOverEvent{result})
+		}
 	} else {
 		slog.Info("Invalid Move.", "move", m.ToStr())
 	}
@@ -76,38 +328,698 @@ func (g *ChessGame) makeMoveImpl(m Move) bool {
 }
 
 func (g *ChessGame) UndoPreviousMove() {
-	prev_state, ok := g.history.Pop()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.undo()
+}
+
+func (g *ChessGame) undo() {
+	if g.result.IsOver() {
+		slog.Error("Cannot undo, the game is over.", "result", g.result.ToStr())
+		return
+	}
+	if g.hasRemotePlayer() {
+		slog.Error("Cannot undo, a remote player takes part in the game.")
+		return
+	}
+	g.cancelAIMove()
+	g.cancelPonder()
+	prev_state, ok := g.history.Undo(g.board)
 	if !ok {
 		slog.Error("No more history to undo.")
 		return
 	}
+	g.tree.gameUndo()
 	slog.Info("Restored game to the previous state.")
-	g.Board = prev_state
+	if g.clock != nil {
+		if boardResult(&g.board).IsOver() {
+			// the clock was stopped when the game ended
+			g.clock.Start(g.board.activeColor)
+		}
+		g.clock.Takeback()
+	}
+	g.board = prev_state
+	undone, _ := g.history.Next()
+	g.events.emit(UndoEvent{undone.Move, g.board, g.history.Ply()})
+}
+
+// RedoMove makes the last undone move again.
+func (g *ChessGame) RedoMove() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.redo()
+}
+
+func (g *ChessGame) redo() bool {
+	if g.hasRemotePlayer() {
+		slog.Error("Cannot redo, a remote player takes part in the game.")
+		return false
+	}
+	next, ok := g.history.Next()
+	if !ok {
+		slog.Error("No more history to redo.")
+		return false
+	}
+	g.cancelAIMove()
+	g.cancelPonder()
+	return g.makeMoveImpl(next.Move)
+}
+
+// JumpToPly undoes or redoes moves until ply half moves of the game are played.
+// Making a move from an earlier ply starts a new line and drops the moves after it.
+// It fails while a remote player takes part in the game.
+func (g *ChessGame) JumpToPly(ply int) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if ply < 0 || ply > g.history.Len() {
+		return false
+	}
+	for g.history.Ply() > ply {
+		prev := g.history.Ply()
+		if g.undo(); g.history.Ply() == prev {
+			return false
+		}
+	}
+	for g.history.Ply() < ply {
+		if !g.redo() {
+			return false
+		}
+	}
+	return true
+}
+
+// Ply returns the number of half moves played to reach the current position.
+func (g *ChessGame) Ply() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.history.Ply()
+}
+
+// History returns the moves of the game, including the undone ones which can be redone.
+// The first Ply of them have been played.
+func (g *ChessGame) History() []HistoryEntry {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.history.Entries()
+}
+
+func (g *ChessGame) CanUndo() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.history.CanUndo() && !g.hasRemotePlayer()
+}
+
+func (g *ChessGame) CanRedo() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.history.CanRedo() && !g.hasRemotePlayer()
+}
+
+// hasRemotePlayer reports whether one of the players is a RemotePlayer. The remote side
+// only learns about the moves made, so moves cannot be taken back or redone then.
+func (g *ChessGame) hasRemotePlayer() bool {
+	for _, player := range g.players {
+		if _, ok := player.(*RemotePlayer); ok {
+			return true
+		}
+	}
+	return false
 }
 
 func (g *ChessGame) GetLegalPieceMoves(sq Square) MoveList {
-	move_list, _ := g.Board.getLegalMoves(sq)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	move_list, _ := g.board.getLegalMoves(sq)
 	return move_list
 }
 
 func (g *ChessGame) GetLegalPieceMovesBB(sq Square) BitBoard {
-	move_list, _ := g.Board.getLegalMoves(sq)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	move_list, _ := g.board.getLegalMoves(sq)
 	return move_list.ToBB()
 }
 
 func (g *ChessGame) GetAllLegalMoves(side Color) MoveList {
-	return g.Board.getAllLegalMoves(side)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.board.getAllLegalMoves(side)
 }
 
+// MakeAIMove searches for the move of the AI and makes it.
+// It blocks until the search has finished, use StartAIMove to search in the background.
 func (g *ChessGame) MakeAIMove() bool {
-	if g.Board.activeColor == g.HumanColor {
-		slog.Error("AI cannot make a move. It's the Human's turn.")
+	g.mu.Lock()
+	if !g.startAIMove(context.Background()) {
+		g.mu.Unlock()
 		return false
 	}
-	if ai_move, found := g.Ai.GetBestMove(&g.Board); found {
-		return g.makeMoveImpl(ai_move)
-	} else {
+	search := g.aiSearch
+	// the game is not locked during the search, so that it can be looked at or stopped
+	g.mu.Unlock()
+	search.Wait()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.aiSearch != search {
+		// the search has been cancelled in the meantime
+		return false
+	}
+	return g.pollAIMove()
+}
+
+// StartAIMove starts searching for the move of the AI in the background.
+// It returns false if it is not the AI's turn or if the AI is already searching.
+// The move is made by PollAIMove once the search has finished.
+func (g *ChessGame) StartAIMove(ctx context.Context) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.startAIMove(ctx)
+}
+
+func (g *ChessGame) startAIMove(ctx context.Context) bool {
+	player, ok := g.playerToMove().(*AIPlayer)
+	if !ok {
+		slog.Error("AI cannot make a move. It's not the turn of an AI.", "player", g.playerToMove().Name())
+		return false
+	}
+	if g.aiSearch != nil || g.isGameOver() || g.isClockPaused() {
+		return false
+	}
+	side, events := g.board.activeColor, g.events
+	g.aiSearch = StartSearch(ctx, player.AI, g.board, func(r SearchResult) {
+		events.emit(SearchProgressEvent{side, r})
+	})
+	if g.clock != nil {
+		time.AfterFunc(g.clock.Budget(g.board.activeColor), g.aiSearch.Stop)
+	}
+	return true
+}
+
+// PollAIMove makes the move of the AI if its search has finished.
+// It does not block and returns true only if a move was made.
+func (g *ChessGame) PollAIMove() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.pollAIMove()
+}
+
+func (g *ChessGame) pollAIMove() bool {
+	if g.aiSearch == nil {
+		return false
+	}
+	if g.checkTime() {
+		return false
+	}
+	result, found, finished := g.aiSearch.Poll()
+	if !finished {
+		return false
+	}
+	g.aiSearch = nil
+	if !found {
 		slog.Info("AI could not find a Move to make.")
 		return false
 	}
+	slog.Info("AI search finished.",
+		"depth", result.Depth, "seldepth", result.SelDepth, "score", ScoreToStr(result.Score),
+		"nodes", result.Nodes, "time", result.Time, "nps", result.NPS, "pv", result.PVToStr())
+	g.lastSearch = &result
+	side := g.board.activeColor
+	player, isAI := g.playerToMove().(*AIPlayer)
+	if isAI {
+		player.observe(result.Score, g.board.fullMoveClock)
+		if player.wantsToResign() {
+			g.resign(side)
+			return false
+		}
+	}
+	if !g.makeMoveImpl(result.Move) {
+		return false
+	}
+	if isAI && player.wantsDraw() && !g.isGameOver() {
+		g.offerDraw(side)
+	}
+	g.startPonder(result)
+	return true
+}
+
+// StopAIMove asks the AI to move now. The best move found so far is made by the next PollAIMove.
+func (g *ChessGame) StopAIMove() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.aiSearch != nil {
+		g.aiSearch.Stop()
+	}
+}
+
+// cancelAIMove stops the search of the AI and discards its result.
+func (g *ChessGame) cancelAIMove() {
+	if g.aiSearch != nil {
+		g.aiSearch.Stop()
+		g.aiSearch.Wait()
+		g.aiSearch = nil
+	}
+}
+
+// SetPondering lets the AI think during the turn of its opponent, unless the opponent is an AI too.
+func (g *ChessGame) SetPondering(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.ponderEnabled = enabled
+	if !enabled {
+		g.cancelPonder()
+	}
+}
+
+func (g *ChessGame) IsPonderingEnabled() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.ponderEnabled
+}
+
+// startPonder starts searching the position after the reply the AI which has just moved
+// expects from its opponent.
+func (g *ChessGame) startPonder(result SearchResult) {
+	if !g.ponderEnabled || len(result.PV) < 2 {
+		return
+	}
+	player, ok := g.players[1^g.board.activeColor].(*AIPlayer)
+	if _, opponentIsAI := g.playerToMove().(*AIPlayer); !ok || opponentIsAI {
+		return
+	}
+	board, err := g.board.MakeMove(result.PV[1])
+	if err != nil {
+		return
+	}
+	slog.Debug("Pondering.", "move", result.PV[1].ToStr())
+	g.ponder = StartPonder(context.Background(), player.AI, board, nil)
+	g.ponderMove = result.PV[1]
+}
+
+// resolvePonder is called after the opponent of the AI has moved. If it played the expected
+// move the ponder search carries on as the AI's search, otherwise it is discarded.
+func (g *ChessGame) resolvePonder(m Move) {
+	if g.ponder == nil {
+		return
+	}
+	if m == g.ponderMove {
+		slog.Debug("Ponder hit.", "move", m.ToStr())
+		var budget time.Duration
+		if g.clock != nil {
+			budget = g.clock.Budget(g.board.activeColor)
+		}
+		g.ponder.PonderHit(budget)
+		g.aiSearch = g.ponder
+		g.ponder = nil
+		return
+	}
+	slog.Debug("Ponder miss.", "expected", g.ponderMove.ToStr(), "played", m.ToStr())
+	g.cancelPonder()
+}
+
+// cancelPonder stops the ponder search and discards its result.
+func (g *ChessGame) cancelPonder() {
+	if g.ponder != nil {
+		g.ponder.Stop()
+		g.ponder.Wait()
+		g.ponder = nil
+	}
+}
+
+func (g *ChessGame) IsAIThinking() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.aiSearch != nil
+}
+
+// AIProgress returns the result of the last completed iteration of the running AI search.
+func (g *ChessGame) AIProgress() (SearchResult, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.aiSearch == nil {
+		return SearchResult{}, false
+	}
+	return g.aiSearch.Progress()
+}
+
+// LastSearchResult returns the result of the search for the last move made by the AI.
+func (g *ChessGame) LastSearchResult() (SearchResult, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.lastSearch == nil {
+		return SearchResult{}, false
+	}
+	return *g.lastSearch, true
+}
+
+// StrengthLimiter is implemented by the AIs whose playing strength can be limited.
+type StrengthLimiter interface {
+	SetStrength(strength Strength)
+	GetStrength() Strength
+}
+
+// strengthLimiters returns the AIs of the players which support limiting their strength.
+func (g *ChessGame) strengthLimiters() []StrengthLimiter {
+	var limiters []StrengthLimiter
+	for _, player := range g.players {
+		if ai, ok := player.(*AIPlayer); ok {
+			if limiter, ok := ai.AI.(StrengthLimiter); ok {
+				limiters = append(limiters, limiter)
+			}
+		}
+	}
+	return limiters
+}
+
+// SetStrength limits the playing strength of the AI players. A running search is cancelled.
+// It returns false if none of the AIs supports limiting its strength.
+func (g *ChessGame) SetStrength(strength Strength) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	limiters := g.strengthLimiters()
+	if len(limiters) == 0 {
+		return false
+	}
+	g.cancelAIMove()
+	g.cancelPonder()
+	for _, limiter := range limiters {
+		limiter.SetStrength(strength)
+	}
+	return true
+}
+
+// GetStrength returns the playing strength of the AI, the one of White if both sides are AIs.
+func (g *ChessGame) GetStrength() (Strength, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	limiters := g.strengthLimiters()
+	if len(limiters) == 0 {
+		return Strength{}, false
+	}
+	return limiters[0].GetStrength(), true
+}
+
+// Player returns the player of side.
+func (g *ChessGame) Player(side Color) Player {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.players[side]
+}
+
+func (g *ChessGame) PlayerToMove() Player {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.playerToMove()
+}
+
+func (g *ChessGame) playerToMove() Player {
+	return g.players[g.board.activeColor]
+}
+
+// IsHumanTurn reports whether the side to move is played by a local human.
+func (g *ChessGame) IsHumanTurn() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.isHumanTurn()
+}
+
+func (g *ChessGame) isHumanTurn() bool {
+	_, ok := g.playerToMove().(*HumanPlayer)
+	return ok
+}
+
+// SetPlayer lets player play side from now on. A running search is cancelled.
+func (g *ChessGame) SetPlayer(side Color, player Player) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.cancelAIMove()
+	g.cancelPonder()
+	g.players[side] = player
+}
+
+// SwapSides lets each player carry on with the pieces of the other.
+func (g *ChessGame) SwapSides() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.cancelAIMove()
+	g.cancelPonder()
+	g.players[White], g.players[Black] = g.players[Black], g.players[White]
+}
+
+// Step lets the player to move make its move if it is not a local human. It starts or
+// polls the search of an AI and makes the move a remote player has sent. It does not
+// block and returns true if a move was made, so front-ends call it once per frame.
+func (g *ChessGame) Step() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.step()
+}
+
+func (g *ChessGame) step() bool {
+	if g.checkTime() || g.isGameOver() {
+		return false
+	}
+	switch player := g.playerToMove().(type) {
+	case *AIPlayer:
+		if by, offered := g.drawOffer(); offered && by != g.board.activeColor {
+			if player.acceptsDraw() {
+				return g.acceptDraw(g.board.activeColor)
+			}
+			g.declineDraw(g.board.activeColor)
+		}
+		if g.aiSearch == nil {
+			g.startAIMove(context.Background())
+			return false
+		}
+		return g.pollAIMove()
+	case *RemotePlayer:
+		if g.isClockPaused() {
+			return false
+		}
+		select {
+		case m, ok := <-player.Moves:
+			if !ok {
+				return false
+			}
+			if !slices.Contains(g.board.GetAllLegalMoves(), m) {
+				slog.Error("The remote player sent an illegal move.", "player", player.Name(), "move", m.ToStr())
+				return false
+			}
+			if !g.makeMoveImpl(m) {
+				return false
+			}
+			g.resolvePonder(m)
+			return true
+		default:
+		}
+	}
+	return false
+}
+
+// Result returns the result of the game, which is Ongoing until the game is over.
+func (g *ChessGame) Result() GameResult {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.gameResult()
+}
+
+func (g *ChessGame) gameResult() GameResult {
+	if g.result.IsOver() {
+		return g.result
+	}
+	return boardResult(&g.board)
+}
+
+func (g *ChessGame) IsGameOver() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.isGameOver()
+}
+
+func (g *ChessGame) isGameOver() bool {
+	return g.gameResult().IsOver()
+}
+
+// Clock returns the clock of the game, or nil if it is played without a time control.
+func (g *ChessGame) Clock() *Clock {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.clock
+}
+
+// CheckTime ends the game if the side to move has run out of time and reports whether it did.
+// Flag falls are also noticed when a move is made, but only CheckTime notices them while
+// the side to move is thinking.
+func (g *ChessGame) CheckTime() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.checkTime()
+}
+
+func (g *ChessGame) checkTime() bool {
+	if g.clock == nil || g.result.IsOver() {
+		return false
+	}
+	if _, flagged := g.clock.Flagged(); !flagged {
+		return false
+	}
+	g.flagFell()
+	return true
+}
+
+// flagFell ends the game lost on time by the side to move.
+func (g *ChessGame) flagFell() {
+	slog.Info("Flag fell.", "side", g.board.activeColor.ToStr())
+	g.end(timeForfeitResult(&g.board, g.board.activeColor))
+}
+
+// end ends the game with result, which the rules of chess did not decide on the board.
+func (g *ChessGame) end(result GameResult) {
+	g.cancelAIMove()
+	g.cancelPonder()
+	if g.clock != nil {
+		g.clock.Stop()
+	}
+	g.result = result
+	g.drawOffered = false
+	g.history.Truncate()
+	slog.Info("Game over.", "result", result.ToStr())
+	g.events.emit(GameOverEvent{result})
+}
+
+// Resign ends the game lost by side.
+func (g *ChessGame) Resign(side Color) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resign(side)
+}
+
+func (g *ChessGame) resign(side Color) bool {
+	if g.isGameOver() {
+		return false
+	}
+	g.end(GameResult{winFor(1 ^ side), Resignation})
+	return true
+}
+
+// OfferDraw lets side offer a draw to its opponent. The offer stands until the
+// opponent accepts or declines it, or makes a move.
+func (g *ChessGame) OfferDraw(side Color) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.offerDraw(side)
+}
+
+func (g *ChessGame) offerDraw(side Color) bool {
+	if g.isGameOver() || g.drawOffered {
+		return false
+	}
+	slog.Info("Draw offered.", "side", side.ToStr())
+	g.drawOfferBy = side
+	g.drawOffered = true
+	g.events.emit(DrawOfferEvent{side})
+	return true
+}
+
+// DrawOffer returns the side which has offered a draw, if there is an offer.
+func (g *ChessGame) DrawOffer() (Color, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.drawOffer()
+}
+
+func (g *ChessGame) drawOffer() (Color, bool) {
+	return g.drawOfferBy, g.drawOffered
+}
+
+// AcceptDraw ends the game in a draw if the opponent of side has offered one.
+func (g *ChessGame) AcceptDraw(side Color) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.acceptDraw(side)
+}
+
+func (g *ChessGame) acceptDraw(side Color) bool {
+	if g.isGameOver() || !g.drawOffered || g.drawOfferBy == side {
+		return false
+	}
+	g.end(GameResult{Draw, DrawAgreement})
+	return true
+}
+
+// DeclineDraw turns down the draw the opponent of side has offered.
+func (g *ChessGame) DeclineDraw(side Color) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.declineDraw(side)
+}
+
+func (g *ChessGame) declineDraw(side Color) bool {
+	if !g.drawOffered || g.drawOfferBy == side {
+		return false
+	}
+	slog.Info("Draw declined.", "side", side.ToStr())
+	g.drawOffered = false
+	return true
+}
+
+// CanClaimDraw returns the rule by which the side to move can claim a draw, if there is one.
+func (g *ChessGame) CanClaimDraw() (Termination, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.canClaimDraw()
+}
+
+func (g *ChessGame) canClaimDraw() (Termination, bool) {
+	switch {
+	case g.isGameOver():
+		return NotTerminated, false
+	case g.history.repetitions(&g.board) >= 3:
+		return ThreefoldRepetition, true
+	case g.board.IsFiftyMoveDraw():
+		return FiftyMoveRule, true
+	}
+	return NotTerminated, false
+}
+
+// ClaimDraw ends the game in a draw if the side to move is entitled to claim one.
+func (g *ChessGame) ClaimDraw() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	rule, ok := g.canClaimDraw()
+	if !ok {
+		return false
+	}
+	g.end(GameResult{Draw, rule})
+	return true
+}
+
+// PauseClock stops the clock of the side to move. A running search of the AI is cancelled
+// and no new one is started until the clock is resumed.
+func (g *ChessGame) PauseClock() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.clock == nil {
+		return
+	}
+	g.cancelAIMove()
+	g.cancelPonder()
+	g.clock.Pause()
+}
+
+func (g *ChessGame) ResumeClock() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.clock != nil {
+		g.clock.Resume()
+	}
+}
+
+func (g *ChessGame) IsClockPaused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.isClockPaused()
+}
+
+func (g *ChessGame) isClockPaused() bool {
+	return g.clock != nil && g.clock.IsPaused()
 }
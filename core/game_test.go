@@ -0,0 +1,302 @@
+package core
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestAI(depth uint8) *NegaMaxAI {
+	ai := NewNegaMaxAI()
+	ai.SetDepth(depth)
+	ai.SetHashSize(1)
+	ai.Threads = 2
+	return &ai
+}
+
+// runConcurrently runs each of fns n times on its own goroutine and fails the test if
+// they do not finish in time, which would be a deadlock.
+func runConcurrently(t *testing.T, n int, fns ...func(rng *rand.Rand)) {
+	t.Helper()
+	var wg sync.WaitGroup
+	for i, fn := range fns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(i)))
+			for range n {
+				fn(rng)
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Minute):
+		t.Fatal("The goroutines did not finish, the game is deadlocked.")
+	}
+}
+
+// checkGameState checks that the position of g is the one reached by the moves in its history.
+func checkGameState(t *testing.T, g *ChessGame) {
+	t.Helper()
+	board := g.history.Start()
+	for _, m := range g.history.Moves() {
+		var err error
+		if board, err = board.MakeMove(m); err != nil {
+			t.Fatalf("The history has an illegal move %s.", m.ToStr())
+		}
+	}
+	if got := g.Snapshot(); got != board {
+		t.Errorf("The position of the game does not match its history:\n%s\n%s", got.ToFen(), board.ToFen())
+	}
+	if board.hash != board.calculateHash() {
+		t.Error("The hash of the position is wrong.")
+	}
+}
+
+// makeRandomMove makes a random legal move if it is the turn of the human.
+func makeRandomMove(g *ChessGame, rng *rand.Rand) {
+	board := g.Snapshot()
+	moves := board.GetAllLegalMoves()
+	if len(moves) == 0 {
+		return
+	}
+	m := moves[rng.Intn(len(moves))]
+	g.MakeMove(m.from, m.to, Queen)
+}
+
+func TestGameConcurrentUse(t *testing.T) {
+	g, err := NewGame(WithPlayers(NewHumanPlayer(""), NewAIPlayer("", newTestAI(3))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetPondering(true)
+
+	var mu sync.Mutex
+	moveEvents := 0
+	unsubscribe := g.Subscribe(func(e Event) {
+		// listeners may use the game while it goes on
+		g.Snapshot()
+		if _, ok := e.(MoveEvent); ok {
+			mu.Lock()
+			moveEvents++
+			mu.Unlock()
+		}
+	})
+	defer unsubscribe()
+
+	runConcurrently(t, 200,
+		func(rng *rand.Rand) {
+			makeRandomMove(g, rng)
+		},
+		func(rng *rand.Rand) {
+			switch rng.Intn(4) {
+			case 0:
+				g.UndoPreviousMove()
+			case 1:
+				g.RedoMove()
+			case 2:
+				g.JumpToPly(rng.Intn(g.Ply() + 1))
+			default:
+				time.Sleep(time.Millisecond)
+			}
+		},
+		func(rng *rand.Rand) {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			if g.StartAIMove(ctx) {
+				for !g.PollAIMove() && g.IsAIThinking() {
+					time.Sleep(time.Millisecond)
+				}
+			}
+		},
+		func(rng *rand.Rand) {
+			if rng.Intn(10) == 0 {
+				g.MakeAIMove()
+			}
+		},
+		func(rng *rand.Rand) {
+			board := g.Snapshot()
+			if board.hash != board.calculateHash() {
+				t.Error("A snapshot has a wrong hash.")
+			}
+			g.History()
+			g.AIProgress()
+			g.Result()
+		},
+		func(rng *rand.Rand) {
+			g.ToPGN(nil)
+			g.WithGameTree(func(tree *GameTree) {
+				tree.GoToEnd()
+			})
+			if _, err := g.MarshalJSON(); err != nil {
+				t.Error(err)
+			}
+		},
+		func(rng *rand.Rand) {
+			events, stop := g.SubscribeChan(4)
+			select {
+			case <-events:
+			case <-time.After(time.Millisecond):
+			}
+			stop()
+		},
+	)
+
+	g.StopAIMove()
+	for g.IsAIThinking() {
+		g.PollAIMove()
+	}
+	g.FlushEvents()
+	checkGameState(t, g)
+	mu.Lock()
+	defer mu.Unlock()
+	if moveEvents == 0 {
+		t.Error("No moves have been made.")
+	}
+}
+
+func TestGameSearchCancelledWhileWaiting(t *testing.T) {
+	g, err := NewGame(WithPlayers(NewAIPlayer("", newTestAI(MaxDepth)), NewHumanPlayer("")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := make(chan bool)
+	go func() {
+		moved <- g.MakeAIMove()
+	}()
+	for !g.IsAIThinking() {
+		time.Sleep(time.Millisecond)
+	}
+	// the game is not locked during the search, so the other side can take over meanwhile
+	g.SetPlayer(White, NewHumanPlayer(""))
+	select {
+	case ok := <-moved:
+		if ok {
+			t.Error("MakeAIMove made a move after its search was cancelled.")
+		}
+	case <-time.After(time.Minute):
+		t.Fatal("MakeAIMove did not return after its search was cancelled.")
+	}
+	if g.Ply() != 0 {
+		t.Errorf("%d moves have been made, want 0.", g.Ply())
+	}
+	checkGameState(t, g)
+}
+
+func TestGameConcurrentSteps(t *testing.T) {
+	white, black := NewAIPlayer("", newTestAI(2)), NewAIPlayer("", newTestAI(2))
+	g, err := NewGame(WithPlayers(white, black))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var moves []MoveEvent
+	unsubscribe := g.Subscribe(func(e Event) {
+		if e, ok := e.(MoveEvent); ok {
+			mu.Lock()
+			moves = append(moves, e)
+			mu.Unlock()
+		}
+	})
+	defer unsubscribe()
+
+	runConcurrently(t, 2000,
+		func(*rand.Rand) {
+			g.Step()
+		},
+		func(*rand.Rand) {
+			g.Step()
+		},
+		func(*rand.Rand) {
+			g.Snapshot()
+			time.Sleep(100 * time.Microsecond)
+		},
+	)
+	g.StopAIMove()
+	for g.IsAIThinking() {
+		g.PollAIMove()
+	}
+	g.FlushEvents()
+	checkGameState(t, g)
+
+	// every move is made once, by the side to move
+	mu.Lock()
+	defer mu.Unlock()
+	for i, e := range moves {
+		if e.Ply != i+1 {
+			t.Fatalf("Move %s was made as ply %d, want %d.", e.SAN, e.Ply, i+1)
+		}
+		if want := Color(i % 2); e.Side != want {
+			t.Fatalf("Ply %d was made by %s, want %s.", e.Ply, e.Side.ToStr(), want.ToStr())
+		}
+	}
+	if len(moves) != g.Ply() {
+		t.Errorf("%d MoveEvents were sent for %d moves.", len(moves), g.Ply())
+	}
+}
+
+func TestGamePonderAgainstRemotePlayer(t *testing.T) {
+	moves := make(chan Move, 1)
+	g, err := NewGame(WithPlayers(NewAIPlayer("", newTestAI(3)), &RemotePlayer{Moves: moves}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetPondering(true)
+	pondered := 0
+	for ply := 0; ply < 12 && !g.IsGameOver(); ply += 2 {
+		deadline := time.Now().Add(time.Minute)
+		for g.Ply() == ply && !g.IsGameOver() {
+			if time.Now().After(deadline) {
+				t.Fatal("The AI did not move.")
+			}
+			g.Step()
+			time.Sleep(time.Millisecond)
+		}
+		if g.IsGameOver() {
+			break
+		}
+		g.mu.Lock()
+		ponder, ponderMove := g.ponder, g.ponderMove
+		board := g.board
+		g.mu.Unlock()
+		// the remote player alternates between the expected move and another one
+		m := ponderMove
+		if ponder == nil || ply%4 == 2 {
+			for _, other := range board.GetAllLegalMoves() {
+				if other != ponderMove {
+					m = other
+					break
+				}
+			}
+		}
+		if ponder != nil {
+			pondered++
+		}
+		moves <- m
+		if !g.Step() {
+			t.Fatalf("The move %s of the remote player was not made.", m.ToStr())
+		}
+		g.mu.Lock()
+		leftOver := g.ponder != nil
+		g.mu.Unlock()
+		if leftOver {
+			t.Fatal("The ponder search was not resolved by the move of the remote player.")
+		}
+	}
+	if pondered == 0 {
+		t.Error("The AI never pondered.")
+	}
+	g.StopAIMove()
+	for g.IsAIThinking() {
+		g.PollAIMove()
+	}
+	checkGameState(t, g)
+}
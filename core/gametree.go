@@ -0,0 +1,325 @@
+package core
+
+import (
+	"errors"
+	"slices"
+)
+
+// NAG is a Numeric Annotation Glyph of PGN, e.g. 1 for a good move (!).
+type NAG uint8
+
+const (
+	NAGGood        NAG = 1
+	NAGMistake     NAG = 2
+	NAGBrilliant   NAG = 3
+	NAGBlunder     NAG = 4
+	NAGInteresting NAG = 5
+	NAGDubious     NAG = 6
+)
+
+// AnnotationColor is the colour of an arrow or a highlighted square.
+type AnnotationColor uint8
+
+const (
+	Green AnnotationColor = iota
+	Red
+	Yellow
+	Blue
+)
+
+// Arrow is an arrow drawn on the board from one square to another.
+type Arrow struct {
+	From  Square
+	To    Square
+	Color AnnotationColor
+}
+
+// Highlight is a square marked on the board.
+type Highlight struct {
+	Square Square
+	Color  AnnotationColor
+}
+
+// GameNode is a position of a GameTree along with the move which led to it. The first
+// child is the main line and the other children are the variations of it.
+type GameNode struct {
+	// Move and SAN are empty at the root of the tree.
+	Move       Move
+	SAN        string
+	Board      Board
+	Comment    string
+	NAGs       []NAG
+	Arrows     []Arrow
+	Highlights []Highlight
+
+	parent   *GameNode
+	children []*GameNode
+}
+
+func (n *GameNode) Parent() *GameNode {
+	return n.parent
+}
+
+// Children returns the moves from the position, the main line first.
+func (n *GameNode) Children() []*GameNode {
+	return slices.Clone(n.children)
+}
+
+func (n *GameNode) IsRoot() bool {
+	return n.parent == nil
+}
+
+// IsMainLine reports whether the node is on the main line of the tree.
+func (n *GameNode) IsMainLine() bool {
+	for ; n.parent != nil; n = n.parent {
+		if n.parent.children[0] != n {
+			return false
+		}
+	}
+	return true
+}
+
+// Ply returns the number of half moves from the root to the node.
+func (n *GameNode) Ply() int {
+	ply := 0
+	for ; n.parent != nil; n = n.parent {
+		ply++
+	}
+	return ply
+}
+
+func (n *GameNode) AddNAG(nag NAG) {
+	if !slices.Contains(n.NAGs, nag) {
+		n.NAGs = append(n.NAGs, nag)
+	}
+}
+
+// GameTree is a game with its variations and annotations. It keeps track of the node
+// which is being looked at, which moves are added to.
+type GameTree struct {
+	// Tags are the tags of the PGN header.
+	Tags    map[string]string
+	Result  Outcome
+	root    *GameNode
+	current *GameNode
+	// game is the position of the ChessGame the tree belongs to, nil for other trees.
+	// The moves leading to it cannot be deleted.
+	game *GameNode
+}
+
+func NewGameTree(start Board) *GameTree {
+	root := &GameNode{Board: start}
+	return &GameTree{
+		Tags:    map[string]string{},
+		root:    root,
+		current: root,
+	}
+}
+
+// newGameTreeFromHistory returns the tree of a game with history. The moves of the history
+// are the main line, including the ones which can be redone.
+func newGameTreeFromHistory(h *MoveHistory) *GameTree {
+	t := NewGameTree(h.Start())
+	node := t.root
+	for i, e := range h.entries {
+		var err error
+		if node, err = node.addChild(e.Move); err != nil {
+			panic("Error: The history of the game has an illegal move.")
+		}
+		if i < h.Ply() {
+			t.game = node
+		}
+	}
+	if t.game == nil {
+		t.game = t.root
+	}
+	t.current = t.game
+	return t
+}
+
+// WithGameTree calls fn with the tree of the game, which has the moves of the game as its
+// main line along with the variations and annotations added to it. The moves which have
+// been undone and can be redone follow the position of the game in the tree. The game is
+// locked while fn runs, so fn must not call its methods or keep the tree after it returns.
+func (g *ChessGame) WithGameTree(fn func(t *GameTree)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.tree.Result = g.gameResult().Outcome
+	fn(g.tree)
+}
+
+// gameMove follows m from the position of the game. A move which is new to the tree
+// becomes the main line, the moves which could have been redone become a variation.
+func (t *GameTree) gameMove(m Move) {
+	for _, child := range t.game.children {
+		if child.Move == m {
+			t.game = child
+			return
+		}
+	}
+	node, err := t.game.addChild(m)
+	if err != nil {
+		panic("Error: The game made a move which is illegal in its tree.")
+	}
+	t.PromoteToMainLine(node)
+	t.game = node
+}
+
+// gameUndo goes back one move from the position of the game.
+func (t *GameTree) gameUndo() {
+	if t.game.parent != nil {
+		t.game = t.game.parent
+	}
+}
+
+func (t *GameTree) Root() *GameNode {
+	return t.root
+}
+
+func (t *GameTree) Current() *GameNode {
+	return t.current
+}
+
+// GoTo makes node, which has to be a node of the tree, the current node.
+func (t *GameTree) GoTo(node *GameNode) {
+	t.current = node
+}
+
+// Next follows the main line from the current node.
+func (t *GameTree) Next() bool {
+	if len(t.current.children) == 0 {
+		return false
+	}
+	t.current = t.current.children[0]
+	return true
+}
+
+// Previous goes back to the parent of the current node.
+func (t *GameTree) Previous() bool {
+	if t.current.parent == nil {
+		return false
+	}
+	t.current = t.current.parent
+	return true
+}
+
+// EnterVariation goes to the i-th variation of the move after the current node,
+// 0 being the main line.
+func (t *GameTree) EnterVariation(i int) bool {
+	if i < 0 || i >= len(t.current.children) {
+		return false
+	}
+	t.current = t.current.children[i]
+	return true
+}
+
+// GoToStart goes to the root of the tree.
+func (t *GameTree) GoToStart() {
+	t.current = t.root
+}
+
+// GoToEnd follows the main line from the current node to its last move.
+func (t *GameTree) GoToEnd() {
+	for t.Next() {
+	}
+}
+
+// MainLine returns the nodes of the main line, without the root.
+func (t *GameTree) MainLine() []*GameNode {
+	var line []*GameNode
+	for n := t.root; len(n.children) > 0; n = n.children[0] {
+		line = append(line, n.children[0])
+	}
+	return line
+}
+
+// AddMove makes m in the current position and goes to the new node. If m has been made
+// there before, its node is used, otherwise m becomes the main line if there are no moves
+// after the current node yet and a new variation if there are.
+func (t *GameTree) AddMove(m Move) (*GameNode, error) {
+	for _, child := range t.current.children {
+		if child.Move == m {
+			t.current = child
+			return child, nil
+		}
+	}
+	node, err := t.current.addChild(m)
+	if err != nil {
+		return nil, err
+	}
+	t.current = node
+	return node, nil
+}
+
+// addChild makes m in the position of n and adds the new node as its last child.
+func (n *GameNode) addChild(m Move) (*GameNode, error) {
+	board, err := n.Board.MakeMove(m)
+	if err != nil {
+		return nil, err
+	}
+	node := &GameNode{
+		Move:   m,
+		SAN:    n.Board.ToSAN(m),
+		Board:  board,
+		parent: n,
+	}
+	n.children = append(n.children, node)
+	return node, nil
+}
+
+// Game returns the node of the position of the game the tree belongs to, nil if it
+// does not belong to one.
+func (t *GameTree) Game() *GameNode {
+	return t.game
+}
+
+// PromoteToMainLine makes the line through node the main line.
+func (t *GameTree) PromoteToMainLine(node *GameNode) {
+	for ; node.parent != nil; node = node.parent {
+		siblings := node.parent.children
+		i := slices.Index(siblings, node)
+		copy(siblings[1:i+1], siblings[:i])
+		siblings[0] = node
+	}
+}
+
+// PromoteVariation moves the variation of node one place up among its siblings,
+// making it the main line if it is the first variation.
+func (t *GameTree) PromoteVariation(node *GameNode) bool {
+	if node.parent == nil {
+		return false
+	}
+	siblings := node.parent.children
+	i := slices.Index(siblings, node)
+	if i == 0 {
+		return false
+	}
+	siblings[i-1], siblings[i] = siblings[i], siblings[i-1]
+	return true
+}
+
+// DeleteVariation removes node and the moves after it from the tree. If the current
+// node is removed, the parent of node becomes the current node. The moves which have
+// been played in the game of the tree cannot be deleted.
+func (t *GameTree) DeleteVariation(node *GameNode) error {
+	if node.parent == nil {
+		return errors.New("The root of the tree cannot be deleted.")
+	}
+	for n := t.game; n != nil; n = n.parent {
+		if n == node {
+			return errors.New("The moves of the game cannot be deleted.")
+		}
+	}
+	for n := t.current; n != nil; n = n.parent {
+		if n == node {
+			t.current = node.parent
+			break
+		}
+	}
+	parent := node.parent
+	parent.children = slices.DeleteFunc(parent.children, func(child *GameNode) bool {
+		return child == node
+	})
+	node.parent = nil
+	return nil
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTreeTestGame(t *testing.T, moves ...string) *ChessGame {
+	t.Helper()
+	g, err := NewGame(WithPlayers(NewHumanPlayer("Alice"), NewHumanPlayer("Bob")), WithMoves(moves...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func makeSAN(t *testing.T, g *ChessGame, san string) {
+	t.Helper()
+	board := g.Snapshot()
+	m, err := board.ParseSAN(san)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.MakeMove(m.from, m.to, m.GetPromPiece()); !ok {
+		t.Fatalf("Move %s was not made.", san)
+	}
+}
+
+// movetext returns the movetext of a PGN, which follows the empty line after the tags.
+func movetext(pgn string) string {
+	_, moves, _ := strings.Cut(pgn, "\n\n")
+	return strings.TrimSpace(moves)
+}
+
+func TestGameTreeUndoneMoves(t *testing.T) {
+	g := newTreeTestGame(t, "e4", "e5", "Nf3", "Nc6")
+	g.UndoPreviousMove()
+	g.UndoPreviousMove()
+	if got, want := movetext(g.ToPGN(nil)), "1. e4 e5 *"; got != want {
+		t.Errorf("The PGN has the moves %q, want %q.", got, want)
+	}
+	g.WithGameTree(func(tree *GameTree) {
+		if tree.Game().Ply() != 2 {
+			t.Errorf("The game is at ply %d of the tree, want 2.", tree.Game().Ply())
+		}
+		if line := tree.MainLine(); len(line) != 4 {
+			t.Errorf("The main line has %d moves, want the 4 moves which can be redone.", len(line))
+		}
+	})
+
+	if !g.RedoMove() {
+		t.Fatal("The undone move could not be redone.")
+	}
+	if got, want := movetext(g.ToPGN(nil)), "1. e4 e5 2. Nf3 *"; got != want {
+		t.Errorf("The PGN has the moves %q, want %q.", got, want)
+	}
+}
+
+func TestGameTreeKeepsAnalysis(t *testing.T) {
+	g := newTreeTestGame(t, "e4", "e5")
+	g.WithGameTree(func(tree *GameTree) {
+		tree.GoTo(tree.Root().Children()[0])
+		m, err := tree.Current().Board.ParseSAN("c5")
+		if err != nil {
+			t.Fatal(err)
+		}
+		node, err := tree.AddMove(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		node.Comment = "The Sicilian."
+		node.AddNAG(NAGInteresting)
+	})
+	makeSAN(t, g, "Nf3")
+	g.UndoPreviousMove()
+	makeSAN(t, g, "Bc4")
+	if !g.Resign(Black) {
+		t.Fatal("Black could not resign.")
+	}
+	// the game move which was replaced is kept as a variation
+	want := "1. e4 e5 (1... c5 $5 {The Sicilian.}) 2. Bc4 {Black resigns.} (2. Nf3) 1-0"
+	if got := movetext(g.ToPGN(nil)); got != want {
+		t.Errorf("The PGN has the moves %q, want %q.", got, want)
+	}
+	// exporting the game does not change its tree
+	if got := movetext(g.ToPGN(nil)); got != want {
+		t.Errorf("The PGN exported again has the moves %q, want %q.", got, want)
+	}
+	g.WithGameTree(func(tree *GameTree) {
+		if tree.Result != WhiteWins {
+			t.Errorf("The result of the tree is %s, want 1-0.", tree.Result.ToStr())
+		}
+	})
+}
+
+func TestGameTreeDeleteGameMoves(t *testing.T) {
+	g := newTreeTestGame(t, "d4", "d5", "c4")
+	g.UndoPreviousMove()
+	g.WithGameTree(func(tree *GameTree) {
+		line := tree.MainLine()
+		for _, node := range line[:2] {
+			if err := tree.DeleteVariation(node); err == nil {
+				t.Errorf("The game move %s was deleted.", node.SAN)
+			}
+		}
+		// the undone move is not part of the game, it is added again when it is redone
+		if err := tree.DeleteVariation(line[2]); err != nil {
+			t.Error(err)
+		}
+	})
+	if !g.RedoMove() {
+		t.Fatal("The undone move could not be redone.")
+	}
+	if got, want := movetext(g.ToPGN(nil)), "1. d4 d5 2. c4 *"; got != want {
+		t.Errorf("The PGN has the moves %q, want %q.", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package core
+
+// HistoryEntry is a move of the game.
+type HistoryEntry struct {
+	Move Move
+	// SAN is the move in Standard Algebraic Notation.
+	SAN string
+	// undo restores the position the move was made in when it is undone.
+	undo undoInfo
+}
+
+// MoveHistory is the list of moves of a game. Undone moves are kept so that they can be
+// redone until a different move is made in their place, which starts a new line.
+type MoveHistory struct {
+	start   Board
+	entries []HistoryEntry
+	// ply is the number of entries which are played, the ones after it can be redone.
+	ply int
+}
+
+func NewMoveHistory(start Board) MoveHistory {
+	return MoveHistory{start: start}
+}
+
+// Start returns the position the game started from.
+func (h *MoveHistory) Start() Board {
+	return h.start
+}
+
+// Ply returns the number of half moves played.
+func (h *MoveHistory) Ply() int {
+	return h.ply
+}
+
+// Len returns the number of moves in the history, including the ones which can be redone.
+func (h *MoveHistory) Len() int {
+	return len(h.entries)
+}
+
+// Entries returns a copy of all the moves in the history, including the ones which can be redone.
+func (h *MoveHistory) Entries() []HistoryEntry {
+	return append([]HistoryEntry(nil), h.entries...)
+}
+
+// Moves returns the moves played to reach the current position.
+func (h *MoveHistory) Moves() []Move {
+	moves := make([]Move, h.ply)
+	for i, e := range h.entries[:h.ply] {
+		moves[i] = e.Move
+	}
+	return moves
+}
+
+// Push records m as made in before. If m is the move which would be redone, the rest of
+// the line is kept, otherwise it is replaced by m.
+func (h *MoveHistory) Push(m Move, before Board) {
+	if h.ply < len(h.entries) && h.entries[h.ply].Move == m {
+		h.ply++
+		return
+	}
+	h.entries = append(h.entries[:h.ply], HistoryEntry{Move: m, SAN: before.ToSAN(m), undo: before.newUndoInfo(m)})
+	h.ply++
+}
+
+// Undo steps back one move from current, the position after the played moves, and
+// returns the position the move was made in.
+func (h *MoveHistory) Undo(current Board) (Board, bool) {
+	if h.ply == 0 {
+		return Board{}, false
+	}
+	h.ply--
+	e := &h.entries[h.ply]
+	return current.unmakeMove(e.Move, e.undo), true
+}
+
+// Next returns the move which would be redone.
+func (h *MoveHistory) Next() (HistoryEntry, bool) {
+	if h.ply == len(h.entries) {
+		return HistoryEntry{}, false
+	}
+	return h.entries[h.ply], true
+}
+
+func (h *MoveHistory) CanUndo() bool {
+	return h.ply > 0
+}
+
+func (h *MoveHistory) CanRedo() bool {
+	return h.ply < len(h.entries)
+}
+
+// repetitions returns how often b, the position after the played moves, has occurred in the game.
+func (h *MoveHistory) repetitions(b *Board) int {
+	n := 1
+	// positions before the last capture or pawn move cannot occur again
+	for i := h.ply - 1; i >= 0 && h.ply-i <= int(b.halfMoveClock); i-- {
+		if h.entries[i].undo.hash == b.hash {
+			n++
+		}
+	}
+	return n
+}
+
+// Truncate drops the moves which can be redone.
+func (h *MoveHistory) Truncate() {
+	h.entries = h.entries[:h.ply]
+}
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"context"
+	"errors"
+)
+
+// MateTree is a proven mate. Move is the attacker's move and Defences holds every legal
+// reply of the defender together with the mate which follows it.
+// Defences is empty if Move is checkmate.
+type MateTree struct {
+	Move Move
+	// Depth is the number of attacker moves until mate, including Move.
+	Depth    int
+	Defences []MateDefence
+}
+
+type MateDefence struct {
+	Move Move
+	Mate MateTree
+}
+
+// MateSolution is the result of FindMate.
+type MateSolution struct {
+	// Tree is the shortest mate found.
+	Tree MateTree
+	// Cooks are the other first moves which also mate within the asked number of moves.
+	Cooks []MateTree
+	// Nodes is the number of positions searched.
+	Nodes uint64
+}
+
+// Line returns the main line of the mate, the defender always chooses the longest defence.
+func (t MateTree) Line() []Move {
+	line := []Move{t.Move}
+	for len(t.Defences) > 0 {
+		longest := t.Defences[0]
+		for _, d := range t.Defences[1:] {
+			if d.Mate.Depth > longest.Mate.Depth {
+				longest = d
+			}
+		}
+		line = append(line, longest.Move, longest.Mate.Move)
+		t = longest.Mate
+	}
+	return line
+}
+
+// LineToStr returns the main line as a space separated list of moves.
+func (t MateTree) LineToStr() string {
+	line := PVLine{PV: t.Line()}
+	return line.PVToStr()
+}
+
+// FindMate proves or disproves that the side to move mates within n moves.
+// Only checking moves are tried for the attacker, so mates which start with
+// or contain a quiet move are not found. All replies of the defender are considered.
+// It returns false if there is no such mate.
+func FindMate(b *Board, n int) (MateSolution, bool) {
+	solution, found, _ := FindMateContext(context.Background(), b, n)
+	return solution, found
+}
+
+// FindMateContext is FindMate which gives up with ctx's error once ctx is done.
+func FindMateContext(ctx context.Context, b *Board, n int) (MateSolution, bool, error) {
+	if n < 1 {
+		return MateSolution{}, false, errors.New("The number of moves has to be at least 1.")
+	}
+	s := mateSearcher{ctx: ctx}
+	var solutions []MateTree
+	for _, m := range s.checkingMoves(b) {
+		// find the shortest mate starting with m
+		for depth := 1; depth <= n; depth++ {
+			tree, ok := s.tryMove(b, m, depth)
+			if s.err != nil {
+				return MateSolution{}, false, s.err
+			}
+			if ok {
+				solutions = append(solutions, tree)
+				break
+			}
+		}
+	}
+	if len(solutions) == 0 {
+		return MateSolution{Nodes: s.nodes}, false, nil
+	}
+	best := 0
+	for i, tree := range solutions {
+		if tree.Depth < solutions[best].Depth {
+			best = i
+		}
+	}
+	solution := MateSolution{Tree: solutions[best], Nodes: s.nodes}
+	for i, tree := range solutions {
+		if i != best {
+			solution.Cooks = append(solution.Cooks, tree)
+		}
+	}
+	return solution, true, nil
+}
+
+type mateSearcher struct {
+	ctx   context.Context
+	nodes uint64
+	err   error
+}
+
+// checkingMoves returns the legal moves of the side to move which give check.
+func (s *mateSearcher) checkingMoves(b *Board) []Move {
+	var checks []Move
+	for _, m := range b.getAllLegalMoves(b.activeColor) {
+		next, ok := b.makeMove(m)
+		if ok && next.isActiveSideInCheck() {
+			checks = append(checks, m)
+		}
+	}
+	return checks
+}
+
+// mate returns the shortest mate within depth moves of the side to move.
+func (s *mateSearcher) mate(b *Board, depth int) (MateTree, bool) {
+	moves := s.checkingMoves(b)
+	for d := 1; d <= depth; d++ {
+		for _, m := range moves {
+			if tree, ok := s.tryMove(b, m, d); ok {
+				return tree, true
+			}
+			if s.err != nil {
+				return MateTree{}, false
+			}
+		}
+	}
+	return MateTree{}, false
+}
+
+// tryMove checks if m mates within depth moves against every defence.
+func (s *mateSearcher) tryMove(b *Board, m Move, depth int) (MateTree, bool) {
+	s.nodes++
+	if s.nodes%1024 == 0 && s.ctx.Err() != nil {
+		s.err = s.ctx.Err()
+	}
+	if s.err != nil {
+		return MateTree{}, false
+	}
+	next, ok := b.makeMove(m)
+	if !ok {
+		return MateTree{}, false
+	}
+	replies := next.getAllLegalMoves(next.activeColor)
+	if len(replies) == 0 {
+		// checkmate, or stalemate which does not count
+		return MateTree{Move: m, Depth: 1}, next.isActiveSideInCheck()
+	}
+	if depth == 1 {
+		return MateTree{}, false
+	}
+	tree := MateTree{Move: m, Depth: 1}
+	for _, reply := range replies {
+		after, ok := next.makeMove(reply)
+		if !ok {
+			return MateTree{}, false
+		}
+		sub, ok := s.mate(&after, depth-1)
+		if !ok {
+			return MateTree{}, false
+		}
+		tree.Defences = append(tree.Defences, MateDefence{reply, sub})
+		tree.Depth = max(tree.Depth, sub.Depth+1)
+	}
+	return tree, true
+}
@@ -0,0 +1,282 @@
+package core
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// mctsScale converts between centipawns and winning probabilities.
+const mctsScale = 400.0
+
+// mctsProgressInterval is the number of iterations between two progress reports.
+const mctsProgressInterval = 2000
+
+// MCTSAI searches with Monte Carlo Tree Search. Nodes are selected with UCT and leaves are
+// not played out randomly, instead the evaluation of the leaf is converted into a winning
+// probability. Several goroutines grow the same tree, using virtual losses to spread out.
+type MCTSAI struct {
+	evalMethod func(b *Board) int32
+	// Iterations limits the number of iterations of a search, 0 means no limit.
+	Iterations int
+	// MoveTime limits how long a search takes, 0 means no limit.
+	MoveTime time.Duration
+	// Threads is the number of goroutines growing the tree.
+	Threads int
+	// Exploration is the exploration constant of UCT.
+	Exploration float64
+	// root is kept between searches so that the subtree of the new position can be reused.
+	root *mctsNode
+}
+
+func NewMCTSAI() MCTSAI {
+	return MCTSAI{
+		evalMethod:  evaluateBoard,
+		Iterations:  20000,
+		MoveTime:    5 * time.Second,
+		Threads:     runtime.NumCPU(),
+		Exploration: math.Sqrt2,
+	}
+}
+
+type mctsNode struct {
+	mu       sync.Mutex
+	move     Move
+	board    Board
+	parent   *mctsNode
+	children []*mctsNode
+	// untried holds the legal moves which do not have a child yet.
+	untried MoveList
+	// visits counts the iterations which went through the node, value sums up their
+	// results from the point of view of the side which made move.
+	visits   float64
+	value    float64
+	terminal bool
+	depth    int
+}
+
+func newMCTSNode(b Board, move Move, parent *mctsNode) *mctsNode {
+	n := &mctsNode{
+		move:   move,
+		board:  b,
+		parent: parent,
+	}
+	n.untried = orderMoves(&b, b.getAllLegalMoves(b.activeColor))
+	// try the best captures first, they are popped from the back
+	slices.Reverse(n.untried)
+	n.terminal = len(n.untried) == 0
+	if parent != nil {
+		n.depth = parent.depth + 1
+	}
+	return n
+}
+
+// GetBestMove grows the search tree until the iteration or time budget is used up or ctx is done.
+// The most visited move at the root is played.
+func (mcts *MCTSAI) GetBestMove(ctx context.Context, b *Board, progress ProgressFunc) (SearchResult, bool) {
+	root := mcts.reuseRoot(b)
+	if root.terminal {
+		return SearchResult{}, false
+	}
+	if mcts.MoveTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, mcts.MoveTime)
+		defer cancel()
+	}
+
+	start := time.Now()
+	var iterations atomic.Int64
+	var maxDepth atomic.Int64
+	var progressMu sync.Mutex
+	var wg sync.WaitGroup
+	for range max(mcts.Threads, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := iterations.Add(1)
+				if mcts.Iterations > 0 && i > int64(mcts.Iterations) {
+					return
+				}
+				depth := mcts.iterate(root)
+				for d := maxDepth.Load(); int64(depth) > d && !maxDepth.CompareAndSwap(d, int64(depth)); d = maxDepth.Load() {
+				}
+				if progress != nil && i%mctsProgressInterval == 0 {
+					progressMu.Lock()
+					progress(mcts.result(root, start, uint64(i), int(maxDepth.Load())))
+					progressMu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	done := uint64(iterations.Load())
+	if mcts.Iterations > 0 {
+		done = min(done, uint64(mcts.Iterations))
+	}
+	result := mcts.result(root, start, done, int(maxDepth.Load()))
+	return result, len(result.PV) > 0
+}
+
+// reuseRoot returns the node of b from the tree of the previous search if it is
+// one or two plies below the previous root, or a new tree otherwise.
+func (mcts *MCTSAI) reuseRoot(b *Board) *mctsNode {
+	if mcts.root != nil {
+		candidates := []*mctsNode{mcts.root}
+		for _, child := range mcts.root.children {
+			candidates = append(candidates, child)
+			candidates = append(candidates, child.children...)
+		}
+		for _, node := range candidates {
+			if node.board.hash == b.hash {
+				node.parent = nil
+				mcts.root = node
+				return node
+			}
+		}
+	}
+	mcts.root = newMCTSNode(*b, Move{}, nil)
+	return mcts.root
+}
+
+// iterate runs a single selection, expansion, evaluation and backpropagation.
+// It returns the depth of the evaluated node.
+func (mcts *MCTSAI) iterate(root *mctsNode) int {
+	node := root
+	node.mu.Lock()
+	node.visits += 1
+	for {
+		if node.terminal {
+			break
+		}
+		if len(node.untried) > 0 {
+			// expansion
+			move := node.untried[len(node.untried)-1]
+			node.untried = node.untried[:len(node.untried)-1]
+			board, _ := node.board.makeMove(move)
+			child := newMCTSNode(board, move, node)
+			child.visits = 1
+			node.children = append(node.children, child)
+			node.mu.Unlock()
+			node = child
+			node.mu.Lock()
+			break
+		}
+		// selection, the visit added here acts as a virtual loss until the value is backed up
+		child := node.selectChild(mcts.Exploration)
+		node.mu.Unlock()
+		node = child
+		node.mu.Lock()
+		node.visits += 1
+	}
+	result := mcts.evaluate(node)
+	depth := node.depth - root.depth
+	node.mu.Unlock()
+
+	for ; node != nil; node = node.parent {
+		node.mu.Lock()
+		node.value += result
+		node.mu.Unlock()
+		result = 1 - result
+	}
+	return depth
+}
+
+func (n *mctsNode) selectChild(exploration float64) *mctsNode {
+	var best *mctsNode
+	bestScore := math.Inf(-1)
+	logVisits := math.Log(n.visits)
+	for _, child := range n.children {
+		child.mu.Lock()
+		score := child.value/child.visits + exploration*math.Sqrt(logVisits/child.visits)
+		child.mu.Unlock()
+		if score > bestScore {
+			bestScore = score
+			best = child
+		}
+	}
+	return best
+}
+
+// evaluate returns the probability that the side which moved into node wins.
+func (mcts *MCTSAI) evaluate(node *mctsNode) float64 {
+	b := &node.board
+	if node.terminal {
+		if b.isActiveSideInCheck() {
+			return 1
+		}
+		return 0.5
+	}
+	score := mcts.evalMethod(b)
+	if b.activeColor == White {
+		score = -score
+	}
+	return 1 / (1 + math.Exp(-float64(score)/mctsScale))
+}
+
+// result builds a SearchResult from the most visited lines of the tree.
+func (mcts *MCTSAI) result(root *mctsNode, start time.Time, iterations uint64, depth int) SearchResult {
+	root.mu.Lock()
+	children := slices.Clone(root.children)
+	root.mu.Unlock()
+	slices.SortStableFunc(children, func(c1 *mctsNode, c2 *mctsNode) int {
+		return int(visitsOf(c2) - visitsOf(c1))
+	})
+
+	var result SearchResult
+	for _, child := range children {
+		pv := []Move{child.move}
+		for node := mostVisitedChild(child); node != nil; node = mostVisitedChild(node) {
+			pv = append(pv, node.move)
+		}
+		child.mu.Lock()
+		winProbability := child.value / child.visits
+		child.mu.Unlock()
+		result.Lines = append(result.Lines, PVLine{pv, probabilityToScore(winProbability)})
+	}
+	if len(result.Lines) > 0 {
+		result.Move = result.Lines[0].PV[0]
+		result.PV = result.Lines[0].PV
+		result.Score = result.Lines[0].Score
+	}
+	result.Depth = depth
+	result.SelDepth = depth
+	result.Nodes = iterations
+	result.Time = time.Since(start)
+	if result.Time > 0 {
+		result.NPS = uint64(float64(iterations) / result.Time.Seconds())
+	}
+	return result
+}
+
+func visitsOf(n *mctsNode) float64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.visits
+}
+
+func mostVisitedChild(n *mctsNode) *mctsNode {
+	n.mu.Lock()
+	children := slices.Clone(n.children)
+	n.mu.Unlock()
+	var best *mctsNode
+	bestVisits := 0.0
+	for _, child := range children {
+		if visits := visitsOf(child); visits > bestVisits {
+			best = child
+			bestVisits = visits
+		}
+	}
+	return best
+}
+
+// probabilityToScore converts a winning probability back into centipawns.
+func probabilityToScore(p float64) int32 {
+	p = min(max(p, 0.0001), 0.9999)
+	return int32(-mctsScale * math.Log(1/p-1))
+}
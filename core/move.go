@@ -1,6 +1,12 @@
 package core
 
-import "fmt"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
 
 const quietMove uint8 = 0b0000
 const doublePawnPush uint8 = 0b0001
@@ -128,6 +134,112 @@ func (m *Move) SetPromPiece(p promotedPiece) {
 	m.flags |= uint8(p)
 }
 
-func (m *Move) ToStr() string {
+func (m Move) ToStr() string {
+	if m.IsPromotion() {
+		return fmt.Sprintf("%s%s%c", m.from.ToStr(), m.to.ToStr(), "nbrq"[m.GetPromPiece()])
+	}
 	return fmt.Sprintf("%s%s", m.from.ToStr(), m.to.ToStr())
 }
+
+// moveKinds name the kinds of moves in JSON, promotions are named without their piece.
+var moveKinds = map[uint8]string{
+	quietMove:                   "quiet",
+	doublePawnPush:              "double_pawn_push",
+	kingCastle:                  "king_castle",
+	queenCastle:                 "queen_castle",
+	captureMask:                 "capture",
+	epCapture:                   "en_passant",
+	promotionMask:               "promotion",
+	captureMask | promotionMask: "promotion_capture",
+}
+
+type moveJSON struct {
+	From Square `json:"from"`
+	To   Square `json:"to"`
+	Kind string `json:"kind"`
+	// Promotion is the piece a pawn promotes to, as in UCI, e.g. q.
+	Promotion string `json:"promotion,omitempty"`
+}
+
+// MarshalJSON writes the move as an object with the squares and the kind of the move,
+// which is all that is needed to make it again.
+func (m Move) MarshalJSON() ([]byte, error) {
+	j := moveJSON{From: m.from, To: m.to}
+	kind := m.flags
+	if m.IsPromotion() {
+		kind &^= 0b0011
+		j.Promotion = string("nbrq"[m.GetPromPiece()])
+	}
+	name, ok := moveKinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("Invalid move flags: %d", m.flags)
+	}
+	j.Kind = name
+	return json.Marshal(j)
+}
+
+func (m *Move) UnmarshalJSON(data []byte) error {
+	var j moveJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	move := Move{from: j.From, to: j.To}
+	found := false
+	for flags, name := range moveKinds {
+		if name == j.Kind {
+			move.flags, found = flags, true
+		}
+	}
+	if !found {
+		return fmt.Errorf("Invalid move kind: %s", j.Kind)
+	}
+	if move.IsPromotion() {
+		prom := strings.Index("nbrq", j.Promotion)
+		if len(j.Promotion) != 1 || prom < 0 {
+			return fmt.Errorf("Invalid promotion: %s", j.Promotion)
+		}
+		move.SetPromPiece(promotedPiece(prom))
+	} else if j.Promotion != "" {
+		return fmt.Errorf("Promotion in a move of kind %s.", j.Kind)
+	}
+	*m = move
+	return nil
+}
+
+// ParseMove parses a legal move of b in the long algebraic notation used by UCI, e.g. e2e4 or e7e8q.
+func (b *Board) ParseMove(s string) (Move, error) {
+	if len(s) != 4 && len(s) != 5 {
+		return Move{}, fmt.Errorf("Invalid move: %s", s)
+	}
+	from, err := StrToSq(s[0:2])
+	if err != nil {
+		return Move{}, err
+	}
+	to, err := StrToSq(s[2:4])
+	if err != nil {
+		return Move{}, err
+	}
+	move_list, _ := b.getLegalMoves(from)
+	for _, move := range move_list {
+		if move.to != to {
+			continue
+		}
+		if !move.IsPromotion() || (len(s) == 5 && "nbrq"[move.GetPromPiece()] == s[4]) {
+			return move, nil
+		}
+	}
+	return Move{}, fmt.Errorf("Illegal move: %s", s)
+}
+
+// MakeMove returns the board after m has been made, m has to be a legal move.
+func (b Board) MakeMove(m Move) (Board, error) {
+	move_list, _ := b.getLegalMoves(m.from)
+	if !slices.Contains(move_list, m) {
+		return b, errors.New("Illegal move.")
+	}
+	board_copy, ok := b.makeMove(m)
+	if !ok {
+		return b, errors.New("Invalid move.")
+	}
+	return board_copy, nil
+}
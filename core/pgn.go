@@ -0,0 +1,229 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// sevenTagRoster are the tags every PGN has, in the order they are written.
+var sevenTagRoster = [...]string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// pgnLineLength is the length movetext is wrapped at.
+const pgnLineLength = 80
+
+// annotationColorLetters are the letters of the colours in [%cal] and [%csl] commands.
+const annotationColorLetters = "GRYB"
+
+// ToPGN exports the tree as PGN, with the variations nested in the main line.
+// The tags missing from the seven tag roster are written as unknown, and the
+// FEN tag is added when the game does not start from the initial position.
+func (t *GameTree) ToPGN() string {
+	return t.writePGN(t.Tags, t.Result, pgnWriter{})
+}
+
+// writePGN exports the tree with the given tags and result, writing the movetext with w.
+func (t *GameTree) writePGN(treeTags map[string]string, result Outcome, w pgnWriter) string {
+	var buf strings.Builder
+	tags := map[string]string{
+		"Event": "?", "Site": "?", "Date": "????.??.??", "Round": "?", "White": "?", "Black": "?",
+	}
+	for name, value := range treeTags {
+		tags[name] = value
+	}
+	tags["Result"] = result.ToStr()
+	if fen := t.root.Board.ToFen(); fen != StartFen {
+		tags["SetUp"] = "1"
+		tags["FEN"] = fen
+	}
+	writeTag := func(name string) {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(tags[name])
+		fmt.Fprintf(&buf, "[%s \"%s\"]\n", name, value)
+		delete(tags, name)
+	}
+	for _, name := range sevenTagRoster {
+		writeTag(name)
+	}
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		writeTag(name)
+	}
+	buf.WriteByte('\n')
+
+	w.comment(t.root)
+	w.moves(t.root, true)
+	w.tokens = append(w.tokens, result.ToStr())
+	buf.WriteString(w.wrap())
+	buf.WriteByte('\n')
+	return buf.String()
+}
+
+func (n *GameNode) hasComment() bool {
+	return n.Comment != "" || len(n.Arrows) > 0 || len(n.Highlights) > 0
+}
+
+// pgnWriter collects the tokens of movetext.
+type pgnWriter struct {
+	tokens []string
+	// end is the last move of the main line if it is set, the main line is the line
+	// leading to it then. line maps every node before it to the next move of the line.
+	end  *GameNode
+	line map[*GameNode]*GameNode
+	// endComment is added to the comment of end.
+	endComment string
+}
+
+// newPGNWriterTo returns a writer which ends the main line at end.
+func newPGNWriterTo(end *GameNode, endComment string) pgnWriter {
+	w := pgnWriter{end: end, line: map[*GameNode]*GameNode{}, endComment: endComment}
+	for n := end; n.parent != nil; n = n.parent {
+		w.line[n.parent] = n
+	}
+	return w
+}
+
+// mainMove returns the move of the main line after node, nil if there is none.
+func (w *pgnWriter) mainMove(node *GameNode) *GameNode {
+	if w.end != nil {
+		if node == w.end {
+			return nil
+		}
+		if next, ok := w.line[node]; ok {
+			return next
+		}
+	}
+	if len(node.children) == 0 {
+		return nil
+	}
+	return node.children[0]
+}
+
+// moves writes the moves after node. forceNumber writes the move number of the first
+// move even if it is a move of Black, which is needed after comments and variations.
+func (w *pgnWriter) moves(node *GameNode, forceNumber bool) {
+	for main := w.mainMove(node); main != nil; main = w.mainMove(node) {
+		w.move(main, forceNumber)
+		forceNumber = main.hasComment()
+		for _, variation := range node.children {
+			if variation == main {
+				continue
+			}
+			w.tokens = append(w.tokens, "(")
+			w.move(variation, true)
+			w.moves(variation, variation.hasComment())
+			w.tokens = append(w.tokens, ")")
+			forceNumber = true
+		}
+		node = main
+	}
+}
+
+func (w *pgnWriter) move(node *GameNode, forceNumber bool) {
+	before := &node.parent.Board
+	if before.activeColor == White {
+		w.tokens = append(w.tokens, fmt.Sprintf("%d.", before.fullMoveClock))
+	} else if forceNumber {
+		w.tokens = append(w.tokens, fmt.Sprintf("%d...", before.fullMoveClock))
+	}
+	w.tokens = append(w.tokens, node.SAN)
+	for _, nag := range node.NAGs {
+		w.tokens = append(w.tokens, fmt.Sprintf("$%d", nag))
+	}
+	w.comment(node)
+}
+
+// comment writes the comment of node with its arrows and highlights as [%cal] and [%csl] commands.
+func (w *pgnWriter) comment(node *GameNode) {
+	text := node.Comment
+	if node == w.end && w.endComment != "" {
+		text = strings.TrimSpace(text + " " + w.endComment)
+	}
+	if text == "" && len(node.Arrows) == 0 && len(node.Highlights) == 0 {
+		return
+	}
+	var parts []string
+	if len(node.Highlights) > 0 {
+		squares := make([]string, len(node.Highlights))
+		for i, h := range node.Highlights {
+			squares[i] = string(annotationColorLetters[h.Color]) + h.Square.ToStr()
+		}
+		parts = append(parts, "[%csl "+strings.Join(squares, ",")+"]")
+	}
+	if len(node.Arrows) > 0 {
+		arrows := make([]string, len(node.Arrows))
+		for i, a := range node.Arrows {
+			arrows[i] = string(annotationColorLetters[a.Color]) + a.From.ToStr() + a.To.ToStr()
+		}
+		parts = append(parts, "[%cal "+strings.Join(arrows, ",")+"]")
+	}
+	// a comment ends at the first closing brace, so it cannot have one of its own
+	if text := strings.ReplaceAll(text, "}", ")"); text != "" {
+		parts = append(parts, text)
+	}
+	w.tokens = append(w.tokens, strings.Fields("{"+strings.Join(parts, " ")+"}")...)
+}
+
+// wrap joins the tokens into lines of at most pgnLineLength characters.
+// Opening parentheses are joined to the token after them and closing ones to the token before.
+func (w *pgnWriter) wrap() string {
+	var words []string
+	open := ""
+	for _, token := range w.tokens {
+		switch token {
+		case "(":
+			open += "("
+		case ")":
+			words[len(words)-1] += ")"
+		default:
+			words = append(words, open+token)
+			open = ""
+		}
+	}
+	var buf strings.Builder
+	lineLength := 0
+	for _, word := range words {
+		if lineLength > 0 && lineLength+1+len(word) > pgnLineLength {
+			buf.WriteByte('\n')
+			lineLength = 0
+		} else if lineLength > 0 {
+			buf.WriteByte(' ')
+			lineLength++
+		}
+		buf.WriteString(word)
+		lineLength += len(word)
+	}
+	return buf.String()
+}
+
+// ToPGN exports the game as PGN. The names of the players are used unless tags or the
+// tags of the game tree have others, and the TimeControl tag is set for games with a clock.
+// How the game ended is written in the Termination tag and in a comment after the last
+// move. The moves which have been undone are not part of the game, the variations and
+// annotations of the game tree before the position of the game are.
+func (g *ChessGame) ToPGN(tags map[string]string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	pgnTags := map[string]string{
+		"White": g.players[White].Name(),
+		"Black": g.players[Black].Name(),
+	}
+	for _, from := range []map[string]string{g.tree.Tags, tags} {
+		for name, value := range from {
+			pgnTags[name] = value
+		}
+	}
+	if g.clock != nil {
+		pgnTags["TimeControl"] = g.clock.Control().ToStr()
+	}
+	result := g.gameResult()
+	pgnTags["Termination"] = result.pgnTermination()
+	var endComment string
+	if result.IsOver() {
+		endComment = result.Description()
+	}
+	return g.tree.writePGN(pgnTags, result.Outcome, newPGNWriterTo(g.tree.game, endComment))
+}
@@ -1,6 +1,9 @@
 package core
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 type Piece uint8
 type Color uint8
@@ -12,15 +15,39 @@ const (
 
 func (c Color) ToStr() string {
 	switch c {
-	case 0:
-		return "Black"
-	case 1:
+	case White:
 		return "White"
+	case Black:
+		return "Black"
 	default:
 		panic("Invalid Color.")
 	}
 }
 
+// MarshalText writes the colour as white or black.
+func (c Color) MarshalText() ([]byte, error) {
+	switch c {
+	case White:
+		return []byte("white"), nil
+	case Black:
+		return []byte("black"), nil
+	default:
+		return nil, fmt.Errorf("Invalid color: %d", c)
+	}
+}
+
+func (c *Color) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "white":
+		*c = White
+	case "black":
+		*c = Black
+	default:
+		return fmt.Errorf("Invalid color: %s", text)
+	}
+	return nil
+}
+
 type promotedPiece uint8
 
 const (
@@ -115,3 +142,24 @@ func CharToPiece(c rune) (Piece, error) {
 		return Pb, errors.New("Invalid char input.")
 	}
 }
+
+// MarshalText writes the piece as in FEN, e.g. N for a white knight.
+func (p Piece) MarshalText() ([]byte, error) {
+	if p > Pb {
+		return nil, fmt.Errorf("Invalid piece: %d", p)
+	}
+	return []byte(string(p.Char())), nil
+}
+
+func (p *Piece) UnmarshalText(text []byte) error {
+	runes := []rune(string(text))
+	if len(runes) != 1 {
+		return fmt.Errorf("Invalid piece: %s", text)
+	}
+	parsed, err := CharToPiece(runes[0])
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
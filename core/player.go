@@ -0,0 +1,156 @@
+package core
+
+import "sync"
+
+// Player is one side of a ChessGame.
+type Player interface {
+	Name() string
+}
+
+// HumanPlayer is a person at this computer, who moves with ChessGame.MakeMove.
+type HumanPlayer struct {
+	PlayerName string
+}
+
+func (p *HumanPlayer) Name() string {
+	if p.PlayerName == "" {
+		return "Human"
+	}
+	return p.PlayerName
+}
+
+// AIPlayer lets an AI play a side. The game searches for its moves in the background.
+type AIPlayer struct {
+	PlayerName string
+	AI         AI
+	// Adjudication lets the AI resign and offer draws, it never does by default.
+	Adjudication Adjudication
+
+	// resignCount and drawCount are the numbers of moves in a row the AI has scored
+	// a loss and a draw, lastScore is the score of its last move.
+	resignCount int
+	drawCount   int
+	lastScore   int32
+}
+
+func (p *AIPlayer) Name() string {
+	if p.PlayerName == "" {
+		return "AI"
+	}
+	return p.PlayerName
+}
+
+// Adjudication holds when an AI player gives up or agrees to a draw. The scores
+// are in centipawns and a number of moves of 0 turns the rule off.
+type Adjudication struct {
+	// The AI resigns once its score has been below -ResignScore for ResignMoves moves in a row.
+	ResignScore int32 `json:"resign_score"`
+	ResignMoves int   `json:"resign_moves"`
+	// The AI offers and accepts draws once its score has been within DrawScore of zero
+	// for DrawMoves moves in a row, but not before move DrawMoveNumber. It accepts
+	// draws when it scores a loss as well.
+	DrawScore      int32 `json:"draw_score"`
+	DrawMoves      int   `json:"draw_moves"`
+	DrawMoveNumber int   `json:"draw_move_number"`
+}
+
+// DefaultAdjudication returns the settings with which an AI resigns lost positions
+// and agrees to dead draws.
+func DefaultAdjudication() Adjudication {
+	return Adjudication{
+		ResignScore:    800,
+		ResignMoves:    3,
+		DrawScore:      10,
+		DrawMoves:      8,
+		DrawMoveNumber: 40,
+	}
+}
+
+// observe records score, the score of the move the AI is making at moveNumber.
+func (p *AIPlayer) observe(score int32, moveNumber uint) {
+	p.lastScore = score
+	if score <= -p.Adjudication.ResignScore {
+		p.resignCount++
+	} else {
+		p.resignCount = 0
+	}
+	if int(moveNumber) >= p.Adjudication.DrawMoveNumber && score >= -p.Adjudication.DrawScore && score <= p.Adjudication.DrawScore {
+		p.drawCount++
+	} else {
+		p.drawCount = 0
+	}
+}
+
+func (p *AIPlayer) wantsToResign() bool {
+	return p.Adjudication.ResignMoves > 0 && p.resignCount >= p.Adjudication.ResignMoves
+}
+
+func (p *AIPlayer) wantsDraw() bool {
+	return p.Adjudication.DrawMoves > 0 && p.drawCount >= p.Adjudication.DrawMoves
+}
+
+// acceptsDraw reports whether the AI agrees to a draw its opponent has offered.
+func (p *AIPlayer) acceptsDraw() bool {
+	return p.wantsDraw() || (p.Adjudication.DrawMoves > 0 && p.lastScore < -p.Adjudication.DrawScore)
+}
+
+// RemotePlayer is a player whose moves arrive from somewhere else, e.g. over the network.
+// The game makes the moves it receives on Moves when it is the player's turn, and tells
+// it about the moves of its opponent with Send. Moves cannot be taken back while a remote
+// player takes part in the game.
+type RemotePlayer struct {
+	PlayerName string
+	Moves      <-chan Move
+	// Send may be nil. It is called on a goroutine of the player, in the order the moves
+	// were made, so that a slow connection does not hold up the game.
+	Send func(Move)
+
+	outMu   sync.Mutex
+	outbox  []Move
+	sending bool
+}
+
+// send queues m for Send and returns without waiting for it.
+func (p *RemotePlayer) send(m Move) {
+	if p.Send == nil {
+		return
+	}
+	p.outMu.Lock()
+	defer p.outMu.Unlock()
+	p.outbox = append(p.outbox, m)
+	if !p.sending {
+		p.sending = true
+		go p.flushOutbox()
+	}
+}
+
+// flushOutbox passes the queued moves to Send until there are none left.
+func (p *RemotePlayer) flushOutbox() {
+	p.outMu.Lock()
+	defer p.outMu.Unlock()
+	for len(p.outbox) > 0 {
+		m := p.outbox[0]
+		p.outbox = p.outbox[1:]
+		p.outMu.Unlock()
+		p.Send(m)
+		p.outMu.Lock()
+	}
+	p.sending = false
+}
+
+func (p *RemotePlayer) Name() string {
+	if p.PlayerName == "" {
+		return "Remote"
+	}
+	return p.PlayerName
+}
+
+// NewHumanPlayer returns a local human player.
+func NewHumanPlayer(name string) *HumanPlayer {
+	return &HumanPlayer{PlayerName: name}
+}
+
+// NewAIPlayer returns a player moving with ai.
+func NewAIPlayer(name string, ai AI) *AIPlayer {
+	return &AIPlayer{PlayerName: name, AI: ai}
+}
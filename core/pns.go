@@ -0,0 +1,327 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ProofResult is the outcome of a proof-number search, seen from the side to move.
+type ProofResult uint8
+
+const (
+	// ProofUnknown means that neither side could be proven to force mate within the limits.
+	// This is also the result of drawn positions.
+	ProofUnknown ProofResult = iota
+	// ProofWin means that the side to move forces mate.
+	ProofWin
+	// ProofLoss means that the side to move gets mated.
+	ProofLoss
+)
+
+func (r ProofResult) ToStr() string {
+	switch r {
+	case ProofWin:
+		return "win"
+	case ProofLoss:
+		return "loss"
+	default:
+		return "unknown"
+	}
+}
+
+// pnInfinity is the proof or disproof number of a node which can not be proven or disproven.
+const pnInfinity uint32 = 1 << 30
+
+// ProofOptions limits the memory and the work of a proof-number search.
+type ProofOptions struct {
+	// MaxNodes limits the number of nodes kept in memory at the same time.
+	MaxNodes int
+	// MaxTTEntries limits the number of proven positions kept in the transposition table.
+	MaxTTEntries int
+	// SecondLevel enables PN², which initialises the proof and disproof numbers of new nodes
+	// with a second, discarded proof-number search. It needs far less memory for deep proofs.
+	SecondLevel bool
+	// SecondLevelNodes limits the size of the second level searches.
+	SecondLevelNodes int
+}
+
+const pnMinSecondLevelNodes = 16
+
+func DefaultProofOptions() ProofOptions {
+	return ProofOptions{
+		MaxNodes:         1 << 20,
+		MaxTTEntries:     1 << 20,
+		SecondLevel:      true,
+		SecondLevelNodes: 1 << 12,
+	}
+}
+
+// ProofSearchResult is the outcome of ProofNumberSearch.
+type ProofSearchResult struct {
+	Result ProofResult
+	// Line is the proven line, starting with the move of the side to move.
+	// It may stop early if the transposition table was full.
+	Line  []Move
+	Nodes uint64
+	Time  time.Duration
+}
+
+// LineToStr returns the proven line as a space separated list of moves.
+func (r *ProofSearchResult) LineToStr() string {
+	line := PVLine{PV: r.Line}
+	return line.PVToStr()
+}
+
+// ProofNumberSearch tries to prove that one of the sides forces mate. It first searches for a
+// mate of the side to move and then for a mate of its opponent. The search stops with
+// ProofUnknown when ctx is done or the memory limits of opts are reached.
+func ProofNumberSearch(ctx context.Context, b *Board, opts ProofOptions) (ProofSearchResult, error) {
+	if opts.MaxNodes < 2 {
+		return ProofSearchResult{}, errors.New("The proof-number search needs at least 2 nodes.")
+	}
+	if opts.MaxTTEntries < 0 {
+		return ProofSearchResult{}, errors.New("The number of transposition table entries cannot be negative.")
+	}
+	if opts.SecondLevel && opts.SecondLevelNodes < 2 {
+		return ProofSearchResult{}, errors.New("The second level searches need at least 2 nodes.")
+	}
+	start := time.Now()
+	result := ProofSearchResult{Result: ProofUnknown}
+	for _, outcome := range []ProofResult{ProofWin, ProofLoss} {
+		attacker := b.activeColor
+		if outcome == ProofLoss {
+			attacker = White
+			if b.activeColor == White {
+				attacker = Black
+			}
+		}
+		s := pnSearcher{
+			ctx:      ctx,
+			attacker: attacker,
+			opts:     opts,
+			tt:       make(map[uint64]Move),
+		}
+		root := s.newNode(*b, Move{}, nil)
+		s.search(root, opts.MaxNodes, opts.SecondLevel)
+		result.Nodes += s.nodes
+		if root.pn == 0 {
+			result.Result = outcome
+			result.Line = s.proofLine(*b)
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	result.Time = time.Since(start)
+	return result, nil
+}
+
+type pnNode struct {
+	board    Board
+	move     Move
+	parent   *pnNode
+	children []*pnNode
+	// pn is the least number of leaves which have to be proven to prove the node,
+	// dn the least number which have to be disproven to disprove it.
+	pn, dn   uint32
+	expanded bool
+	ply      int
+}
+
+func (n *pnNode) solved() bool {
+	return n.pn == 0 || n.dn == 0
+}
+
+type pnSearcher struct {
+	ctx      context.Context
+	attacker Color
+	opts     ProofOptions
+	// tt maps proven positions onto the move which proves them, or onto Move{} if the
+	// position is checkmate. Disproofs are not stored because they can depend on the path
+	// through repetitions, proofs never do.
+	tt    map[uint64]Move
+	nodes uint64
+}
+
+func (s *pnSearcher) newNode(b Board, m Move, parent *pnNode) *pnNode {
+	s.nodes++
+	n := &pnNode{board: b, move: m, parent: parent, pn: 1, dn: 1}
+	if parent != nil {
+		n.ply = parent.ply + 1
+	}
+	if _, ok := s.tt[b.hash]; ok {
+		n.pn, n.dn = 0, pnInfinity
+		return n
+	}
+	if n.ply >= maxPly || s.isRepetition(n) {
+		n.pn, n.dn = pnInfinity, 0
+		return n
+	}
+	moves := len(b.getAllLegalMoves(b.activeColor))
+	switch {
+	case moves == 0 && b.isActiveSideInCheck() && b.activeColor != s.attacker:
+		n.pn, n.dn = 0, pnInfinity
+		s.storeProof(n, Move{})
+	case moves == 0:
+		// the attacker is mated or it is stalemate
+		n.pn, n.dn = pnInfinity, 0
+	case b.activeColor == s.attacker:
+		// the more moves the attacker has the harder it is to disprove the node
+		n.dn = uint32(moves)
+	default:
+		n.pn = uint32(moves)
+	}
+	return n
+}
+
+// isRepetition checks if the position of n occurred before on the path from the root.
+// A repetition is a draw, which disproves the node.
+func (s *pnSearcher) isRepetition(n *pnNode) bool {
+	for p := n.parent; p != nil; p = p.parent {
+		if p.board.hash == n.board.hash {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *pnSearcher) storeProof(n *pnNode, m Move) {
+	if len(s.tt) < s.opts.MaxTTEntries {
+		s.tt[n.board.hash] = m
+	}
+}
+
+// search grows the tree below root until root is solved, the tree has maxNodes nodes or ctx is done.
+func (s *pnSearcher) search(root *pnNode, maxNodes int, secondLevel bool) {
+	size := 1
+	for i := 0; !root.solved() && size < maxNodes; i++ {
+		if i%256 == 0 && s.ctx.Err() != nil {
+			return
+		}
+		n := root.mostProving(s.attacker)
+		size += s.expand(n, size, secondLevel)
+		size -= s.update(n, root)
+	}
+}
+
+// mostProving descends to the leaf whose solution changes the proof or disproof number of the root the most.
+func (n *pnNode) mostProving(attacker Color) *pnNode {
+	for n.expanded {
+		var best *pnNode
+		for _, child := range n.children {
+			if best == nil ||
+				(n.board.activeColor == attacker && child.pn < best.pn) ||
+				(n.board.activeColor != attacker && child.dn < best.dn) {
+				best = child
+			}
+		}
+		n = best
+	}
+	return n
+}
+
+// expand creates the children of n and returns the number of nodes added to the tree.
+// With secondLevel the numbers of the children come from a second search below n,
+// whose size grows with the size of the tree. Only the children are kept of it. When the
+// budget left for the second search is too small to expand n, n is expanded directly.
+func (s *pnSearcher) expand(n *pnNode, size int, secondLevel bool) int {
+	budget := min(max(size, pnMinSecondLevelNodes), s.opts.SecondLevelNodes, s.opts.MaxNodes-size)
+	if secondLevel && budget >= 2 {
+		s.search(n, budget, false)
+		if !n.expanded {
+			return 0
+		}
+		for _, child := range n.children {
+			child.children = nil
+			child.expanded = false
+		}
+		return len(n.children)
+	}
+	for _, m := range n.board.getAllLegalMoves(n.board.activeColor) {
+		b, ok := n.board.makeMove(m)
+		if ok {
+			n.children = append(n.children, s.newNode(b, m, n))
+		}
+	}
+	n.expanded = true
+	return len(n.children)
+}
+
+// update recalculates the numbers from n up to root and frees the subtrees of solved nodes.
+// It returns the number of freed nodes.
+func (s *pnSearcher) update(n *pnNode, root *pnNode) int {
+	freed := 0
+	for ; n != nil; n = n.parent {
+		if n.expanded && !n.solved() {
+			s.setNumbers(n)
+			if n.solved() {
+				for _, child := range n.children {
+					freed += child.size()
+				}
+				n.children = nil
+			}
+		}
+		if n == root {
+			break
+		}
+	}
+	return freed
+}
+
+func (s *pnSearcher) setNumbers(n *pnNode) {
+	var proof Move
+	if n.board.activeColor == s.attacker {
+		// one proven move is enough, all moves have to be disproven
+		n.pn, n.dn = pnInfinity, 0
+		for _, child := range n.children {
+			if child.pn < n.pn {
+				n.pn = child.pn
+				proof = child.move
+			}
+			n.dn = min(n.dn+child.dn, pnInfinity)
+		}
+	} else {
+		n.pn, n.dn = 0, pnInfinity
+		for _, child := range n.children {
+			n.pn = min(n.pn+child.pn, pnInfinity)
+			if child.dn < n.dn {
+				n.dn = child.dn
+			}
+		}
+		if len(n.children) > 0 {
+			proof = n.children[0].move
+		}
+	}
+	if n.pn == 0 {
+		s.storeProof(n, proof)
+	}
+}
+
+func (n *pnNode) size() int {
+	size := 1
+	for _, child := range n.children {
+		size += child.size()
+	}
+	return size
+}
+
+// proofLine follows the proven moves of the transposition table from b.
+// The defender's moves are the first legal ones, not necessarily the longest defence.
+func (s *pnSearcher) proofLine(b Board) []Move {
+	var line []Move
+	for range maxPly {
+		m, ok := s.tt[b.hash]
+		if !ok || m == (Move{}) {
+			break
+		}
+		next, ok := b.makeMove(m)
+		if !ok {
+			break
+		}
+		line = append(line, m)
+		b = next
+	}
+	return line
+}
@@ -0,0 +1,191 @@
+package core
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// Outcome is who won a game, if it is over.
+type Outcome uint8
+
+const (
+	Ongoing Outcome = iota
+	WhiteWins
+	BlackWins
+	Draw
+)
+
+// winFor returns the outcome of a game won by side.
+func winFor(side Color) Outcome {
+	if side == White {
+		return WhiteWins
+	}
+	return BlackWins
+}
+
+// ToStr returns the outcome as in the result of a PGN.
+func (o Outcome) ToStr() string {
+	switch o {
+	case WhiteWins:
+		return "1-0"
+	case BlackWins:
+		return "0-1"
+	case Draw:
+		return "1/2-1/2"
+	default:
+		return "*"
+	}
+}
+
+// MarshalText writes the outcome as in PGN.
+func (o Outcome) MarshalText() ([]byte, error) {
+	return []byte(o.ToStr()), nil
+}
+
+func (o *Outcome) UnmarshalText(text []byte) error {
+	for outcome := Ongoing; outcome <= Draw; outcome++ {
+		if outcome.ToStr() == string(text) {
+			*o = outcome
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid outcome: %s", text)
+}
+
+// Termination is the reason a game ended.
+type Termination uint8
+
+const (
+	NotTerminated Termination = iota
+	Checkmate
+	Stalemate
+	InsufficientMaterial
+	TimeForfeit
+	Resignation
+	DrawAgreement
+	FiftyMoveRule
+	ThreefoldRepetition
+)
+
+func (t Termination) ToStr() string {
+	switch t {
+	case Checkmate:
+		return "checkmate"
+	case Stalemate:
+		return "stalemate"
+	case InsufficientMaterial:
+		return "insufficient material"
+	case TimeForfeit:
+		return "time forfeit"
+	case Resignation:
+		return "resignation"
+	case DrawAgreement:
+		return "draw agreement"
+	case FiftyMoveRule:
+		return "fifty move rule"
+	case ThreefoldRepetition:
+		return "threefold repetition"
+	default:
+		return "unterminated"
+	}
+}
+
+func (t Termination) MarshalText() ([]byte, error) {
+	return []byte(t.ToStr()), nil
+}
+
+func (t *Termination) UnmarshalText(text []byte) error {
+	for termination := NotTerminated; termination <= ThreefoldRepetition; termination++ {
+		if termination.ToStr() == string(text) {
+			*t = termination
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid termination: %s", text)
+}
+
+type GameResult struct {
+	Outcome     Outcome
+	Termination Termination
+}
+
+func (r GameResult) IsOver() bool {
+	return r.Outcome != Ongoing
+}
+
+func (r GameResult) ToStr() string {
+	if !r.IsOver() {
+		return r.Outcome.ToStr()
+	}
+	return r.Outcome.ToStr() + " (" + r.Termination.ToStr() + ")"
+}
+
+// Description describes how the game ended, e.g. "Black resigns.".
+func (r GameResult) Description() string {
+	var winner, loser string
+	switch r.Outcome {
+	case Ongoing:
+		return "The game is in progress."
+	case WhiteWins:
+		winner, loser = White.ToStr(), Black.ToStr()
+	case BlackWins:
+		winner, loser = Black.ToStr(), White.ToStr()
+	}
+	switch r.Termination {
+	case Checkmate:
+		return winner + " mates."
+	case Stalemate:
+		return "Stalemate."
+	case InsufficientMaterial:
+		return "Insufficient material."
+	case TimeForfeit:
+		if r.Outcome == Draw {
+			return "Time forfeit, but the opponent cannot mate."
+		}
+		return winner + " wins on time."
+	case Resignation:
+		return loser + " resigns."
+	case DrawAgreement:
+		return "Draw agreed."
+	case FiftyMoveRule:
+		return "Draw claimed by the fifty move rule."
+	case ThreefoldRepetition:
+		return "Draw claimed by threefold repetition."
+	default:
+		return r.Outcome.ToStr()
+	}
+}
+
+// pgnTermination returns the value of the Termination tag of PGN for the result.
+func (r GameResult) pgnTermination() string {
+	switch {
+	case !r.IsOver():
+		return "unterminated"
+	case r.Termination == TimeForfeit:
+		return "time forfeit"
+	default:
+		return "normal"
+	}
+}
+
+// boardResult returns the result of a game in b if the rules end it there.
+func boardResult(b *Board) GameResult {
+	switch {
+	case b.IsCheckmate():
+		return GameResult{winFor(1 ^ b.activeColor), Checkmate}
+	case b.IsStalemate():
+		return GameResult{Draw, Stalemate}
+	case b.IsInsufficientMaterial():
+		return GameResult{Draw, InsufficientMaterial}
+	}
+	return GameResult{}
+}
+
+// timeForfeitResult returns the result of a game in b lost on time by side. The game
+// is drawn instead if the opponent has only its king left and so cannot mate.
+func timeForfeitResult(b *Board, side Color) GameResult {
+	if bits.OnesCount64(uint64(b.getColorOccupancy(1^side))) == 1 {
+		return GameResult{Draw, TimeForfeit}
+	}
+	return GameResult{winFor(1 ^ side), TimeForfeit}
+}
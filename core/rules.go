@@ -0,0 +1,118 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// Hash returns the zobrist hash of the position. Positions with the same hash are
+// considered to be the same when looking for repetitions.
+func (b *Board) Hash() uint64 {
+	return b.hash
+}
+
+// HalfMoveClock returns the number of half moves since the last capture or pawn move.
+func (b *Board) HalfMoveClock() uint {
+	return b.halfMoveClock
+}
+
+func (b *Board) FullMoveNumber() uint {
+	return b.fullMoveClock
+}
+
+func (b *Board) IsCheck() bool {
+	return b.isActiveSideInCheck()
+}
+
+func (b *Board) IsCheckmate() bool {
+	return b.isActiveSideInCheck() && len(b.getAllLegalMoves(b.activeColor)) == 0
+}
+
+func (b *Board) IsStalemate() bool {
+	return !b.isActiveSideInCheck() && len(b.getAllLegalMoves(b.activeColor)) == 0
+}
+
+// IsFiftyMoveDraw reports whether fifty moves have been made by each side without
+// a capture or a pawn move.
+func (b *Board) IsFiftyMoveDraw() bool {
+	return b.halfMoveClock >= 100
+}
+
+// IsInsufficientMaterial reports whether neither side can possibly mate, which is the case
+// with only kings and at most one minor piece, or with only bishops on squares of one colour.
+func (b *Board) IsInsufficientMaterial() bool {
+	if b.bitBoards[Pw]|b.bitBoards[Pb]|b.bitBoards[Rw]|b.bitBoards[Rb]|b.bitBoards[Qw]|b.bitBoards[Qb] != 0 {
+		return false
+	}
+	knights := b.bitBoards[Nw] | b.bitBoards[Nb]
+	bishops := b.bitBoards[Bw] | b.bitBoards[Bb]
+	minors := bits.OnesCount64(uint64(knights | bishops))
+	if minors <= 1 {
+		return true
+	}
+	if knights != 0 {
+		return false
+	}
+	const darkSquares BitBoard = 0xAA55AA55AA55AA55
+	return bishops&darkSquares == 0 || bishops&^darkSquares == 0
+}
+
+// Validate returns why the board is not a legal position, or nil if it is. Only the basic
+// rules are checked, e.g. that each side has one king and that the side which has just
+// moved is not in check, not whether the position can be reached from the initial one.
+func (b *Board) Validate() error {
+	kings, pawns := [2]Piece{Kw, Kb}, [2]Piece{Pw, Pb}
+	var occupancy BitBoard
+	for p, bb := range b.bitBoards {
+		if occupancy&bb != 0 {
+			return errors.New("The position has two pieces on the same square.")
+		}
+		if Piece(p) == Kw || Piece(p) == Kb {
+			if count := bits.OnesCount64(uint64(bb)); count != 1 {
+				return fmt.Errorf("%s has %d kings.", Piece(p).GetColor().ToStr(), count)
+			}
+		}
+		occupancy |= bb
+	}
+	for _, side := range []Color{White, Black} {
+		sidePawns := b.bitBoards[pawns[side]]
+		if bits.OnesCount64(uint64(sidePawns)) > 8 || bits.OnesCount64(uint64(b.getColorOccupancy(side))) > 16 {
+			return fmt.Errorf("%s has too many pieces.", side.ToStr())
+		}
+		if sidePawns&(FirstRank|EighthRank) != 0 {
+			return fmt.Errorf("%s has a pawn on the first or the last rank.", side.ToStr())
+		}
+	}
+	opponent := b.activeColor ^ 1
+	if king, _ := b.bitBoards[kings[opponent]].Peek(); b.isSqAttacked(king, b.activeColor) {
+		return fmt.Errorf("%s is in check, but it is not its turn.", opponent.ToStr())
+	}
+	castling := []struct {
+		allowed    bool
+		king, rook Piece
+		kingSq     Square
+		rookSq     Square
+	}{
+		{b.CanWhiteOO(), Kw, Rw, E1, H1},
+		{b.CanWhiteOOO(), Kw, Rw, E1, A1},
+		{b.CanBlackOO(), Kb, Rb, E8, H8},
+		{b.CanBlackOOO(), Kb, Rb, E8, A8},
+	}
+	for _, c := range castling {
+		if c.allowed && (!b.bitBoards[c.king].IsSet(c.kingSq) || !b.bitBoards[c.rook].IsSet(c.rookSq)) {
+			return fmt.Errorf("%s cannot castle with the rook on %s.", c.king.GetColor().ToStr(), c.rookSq.ToStr())
+		}
+	}
+	if sq, ok := b.epTarget.get(); ok {
+		// the pawn which has just moved two squares stands in front of the target
+		rank, pushed, from := SixthRank, sq-8, sq+8
+		if b.activeColor == Black {
+			rank, pushed, from = ThirdRank, sq+8, sq-8
+		}
+		if !rank.IsSet(sq) || occupancy.IsSet(sq) || occupancy.IsSet(from) || !b.bitBoards[pawns[opponent]].IsSet(pushed) {
+			return fmt.Errorf("Invalid en-passant target: %s", sq.ToStr())
+		}
+	}
+	return nil
+}
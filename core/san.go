@@ -0,0 +1,166 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sanPieceLetters are the letters of the piece types in SAN, indexed by the piece type.
+const sanPieceLetters = "NBRQK"
+
+// ToSAN returns m, a legal move of b, in Standard Algebraic Notation, e.g. Nf3, exd5, O-O or e8=Q+.
+func (b *Board) ToSAN(m Move) string {
+	var san strings.Builder
+	piece, _ := b.GetAtSq(m.from)
+	pieceType := piece % 6
+	switch {
+	case m.IsKingCastle():
+		san.WriteString("O-O")
+	case m.IsQueenCastle():
+		san.WriteString("O-O-O")
+	case pieceType == Pw:
+		if m.IsCapture() {
+			san.WriteByte(m.from.ToStr()[0])
+			san.WriteByte('x')
+		}
+		san.WriteString(m.to.ToStr())
+		if m.IsPromotion() {
+			san.WriteByte('=')
+			san.WriteByte(sanPieceLetters[m.GetPromPiece()])
+		}
+	default:
+		san.WriteByte(sanPieceLetters[pieceType])
+		san.WriteString(b.sanDisambiguation(m, piece))
+		if m.IsCapture() {
+			san.WriteByte('x')
+		}
+		san.WriteString(m.to.ToStr())
+	}
+
+	if next, ok := b.makeMove(m); ok && next.isActiveSideInCheck() {
+		if len(next.getAllLegalMoves(next.activeColor)) == 0 {
+			san.WriteByte('#')
+		} else {
+			san.WriteByte('+')
+		}
+	}
+	return san.String()
+}
+
+// sanDisambiguation returns the file, the rank or the square of the origin of m if
+// another piece of the same kind can move to the same square.
+func (b *Board) sanDisambiguation(m Move, piece Piece) string {
+	sameFile, sameRank, ambiguous := false, false, false
+	fromX, fromY := m.from.ToXY()
+	for _, other := range b.getAllLegalMoves(b.activeColor) {
+		if other.to != m.to || other.from == m.from {
+			continue
+		}
+		if p, _ := b.GetAtSq(other.from); p != piece {
+			continue
+		}
+		ambiguous = true
+		x, y := other.from.ToXY()
+		sameFile = sameFile || x == fromX
+		sameRank = sameRank || y == fromY
+	}
+	from := m.from.ToStr()
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return from[:1]
+	case !sameRank:
+		return from[1:]
+	default:
+		return from
+	}
+}
+
+// MovesToSAN converts a line of moves starting from b into SAN.
+// The conversion stops at the first illegal move.
+func (b Board) MovesToSAN(moves []Move) []string {
+	sans := make([]string, 0, len(moves))
+	for _, m := range moves {
+		next, err := b.MakeMove(m)
+		if err != nil {
+			break
+		}
+		sans = append(sans, b.ToSAN(m))
+		b = next
+	}
+	return sans
+}
+
+// ParseSAN parses a legal move of b in Standard Algebraic Notation.
+// Check and annotation suffixes are ignored and castling may also be written with zeros.
+func (b *Board) ParseSAN(s string) (Move, error) {
+	san := strings.TrimRight(s, "+#!?")
+	san = strings.ReplaceAll(san, "0", "O")
+	legal := b.getAllLegalMoves(b.activeColor)
+
+	if san == "O-O" || san == "O-O-O" {
+		for _, m := range legal {
+			if (san == "O-O" && m.IsKingCastle()) || (san == "O-O-O" && m.IsQueenCastle()) {
+				return m, nil
+			}
+		}
+		return Move{}, fmt.Errorf("Illegal move: %s", s)
+	}
+
+	// the piece type, pawns have no letter
+	pieceType := Piece(Pw)
+	if len(san) > 0 {
+		if i := strings.IndexByte(sanPieceLetters, san[0]); i >= 0 {
+			pieceType = Piece(i)
+			san = san[1:]
+		}
+	}
+	// the promotion piece, written as e8=Q or e8Q
+	promotion := -1
+	if n := len(san); n > 0 && pieceType == Pw {
+		if i := strings.IndexByte(sanPieceLetters[:4], san[n-1]); i >= 0 {
+			promotion = i
+			san = strings.TrimSuffix(san[:n-1], "=")
+		}
+	}
+	san = strings.ReplaceAll(san, "x", "")
+	san = strings.ReplaceAll(san, "-", "")
+	if len(san) < 2 || len(san) > 4 {
+		return Move{}, fmt.Errorf("Invalid move: %s", s)
+	}
+	to, err := StrToSq(san[len(san)-2:])
+	if err != nil {
+		return Move{}, fmt.Errorf("Invalid move: %s", s)
+	}
+	// what is left is the file, the rank or the square of the origin
+	hint := san[:len(san)-2]
+
+	var found []Move
+	for _, m := range legal {
+		if m.to != to {
+			continue
+		}
+		if p, _ := b.GetAtSq(m.from); p%6 != pieceType {
+			continue
+		}
+		if m.IsPromotion() && int(m.GetPromPiece()) != promotion {
+			continue
+		}
+		if !m.IsPromotion() && promotion >= 0 {
+			continue
+		}
+		if !strings.Contains(m.from.ToStr(), hint) {
+			continue
+		}
+		found = append(found, m)
+	}
+	switch len(found) {
+	case 0:
+		return Move{}, fmt.Errorf("Illegal move: %s", s)
+	case 1:
+		return found[0], nil
+	default:
+		return Move{}, fmt.Errorf("Ambiguous move: %s", s)
+	}
+}
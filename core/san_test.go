@@ -0,0 +1,84 @@
+package core
+
+import "testing"
+
+// promotionFen has promotions by a push, by a capture and with check.
+const promotionFen = "1r5k/P3P3/8/8/8/8/8/K7 w - - 0 1"
+
+func TestParseSANPromotions(t *testing.T) {
+	b, err := BoardFromFen(promotionFen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		san  string
+		want string
+	}{
+		{"a8=Q", "a7a8q"},
+		{"a8=B", "a7a8b"},
+		{"a8=R", "a7a8r"},
+		{"a8=N", "a7a8n"},
+		{"a8Q", "a7a8q"},
+		{"axb8=Q+", "a7b8q"},
+		{"axb8=B", "a7b8b"},
+		{"bxa8=Q", ""},
+		{"e8=Q+", "e7e8q"},
+		{"e8=B", "e7e8b"},
+		{"e8", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.san, func(t *testing.T) {
+			m, err := b.ParseSAN(tt.san)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("ParseSAN returned %s, want an error.", m.ToStr())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.ToStr() != tt.want {
+				t.Errorf("ParseSAN returned %s, want %s.", m.ToStr(), tt.want)
+			}
+			if again, err := b.ParseSAN(b.ToSAN(m)); err != nil || again != m {
+				t.Errorf("The SAN %s of %s does not parse back to it.", b.ToSAN(m), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMovePromotions(t *testing.T) {
+	b, err := BoardFromFen(promotionFen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"a7a8q", "a7a8b", "a7a8r", "a7a8n", "a7b8q", "a7b8b", "e7e8q", "e7e8b"} {
+		m, err := b.ParseMove(s)
+		if err != nil {
+			t.Errorf("ParseMove(%s) failed: %s", s, err)
+			continue
+		}
+		if m.ToStr() != s {
+			t.Errorf("ParseMove(%s) returned %s.", s, m.ToStr())
+		}
+	}
+}
+
+func TestPromotionsGeneratedOnce(t *testing.T) {
+	b, err := BoardFromFen(promotionFen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[Move]bool)
+	for _, m := range b.GetAllLegalMoves() {
+		if seen[m] {
+			t.Errorf("Move %s is generated twice.", m.ToStr())
+		}
+		seen[m] = true
+	}
+	// the king move and four promotions on each of three squares
+	if len(seen) != 13 {
+		t.Errorf("%d legal moves are generated, want 13.", len(seen))
+	}
+}
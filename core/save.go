@@ -0,0 +1,346 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// saveVersion is the version of the format written by ChessGame.MarshalJSON. It has to be
+// increased whenever a change to the format would make UnmarshalJSON misread older saves.
+const saveVersion = 1
+
+// gameJSON is a saved ChessGame.
+type gameJSON struct {
+	Version int   `json:"version"`
+	Start   Board `json:"start"`
+	// Moves are all the moves of the history, of which the first Ply are played.
+	Moves     []Move     `json:"moves"`
+	Ply       int        `json:"ply"`
+	Result    resultJSON `json:"result"`
+	DrawOffer *Color     `json:"draw_offer,omitempty"`
+	Clock     *clockJSON `json:"clock,omitempty"`
+	White     playerJSON `json:"white"`
+	Black     playerJSON `json:"black"`
+	Pondering bool       `json:"pondering"`
+}
+
+type resultJSON struct {
+	Outcome     Outcome     `json:"outcome"`
+	Termination Termination `json:"termination"`
+}
+
+type clockJSON struct {
+	Control string `json:"control"`
+	// Remaining is the time each side had at the start of the current turn and Used
+	// the time the active side has used in it.
+	Remaining  [2]time.Duration `json:"remaining_ns"`
+	Used       time.Duration    `json:"used_ns"`
+	Stage      [2]int           `json:"stage"`
+	StageMoves [2]int           `json:"stage_moves"`
+	History    []clockMoveJSON  `json:"history"`
+	Active     Color            `json:"active"`
+	Running    bool             `json:"running"`
+	Paused     bool             `json:"paused"`
+}
+
+type clockMoveJSON struct {
+	Side       Color         `json:"side"`
+	Remaining  time.Duration `json:"remaining_ns"`
+	Stage      int           `json:"stage"`
+	StageMoves int           `json:"stage_moves"`
+}
+
+type playerJSON struct {
+	// Type is human, ai or remote. An ai has the settings of either NegaMax or MCTS.
+	Type         string        `json:"type"`
+	Name         string        `json:"name,omitempty"`
+	NegaMax      *negaMaxJSON  `json:"negamax,omitempty"`
+	MCTS         *mctsJSON     `json:"mcts,omitempty"`
+	Adjudication *Adjudication `json:"adjudication,omitempty"`
+	ResignCount  int           `json:"resign_count,omitempty"`
+	DrawCount    int           `json:"draw_count,omitempty"`
+	LastScore    int32         `json:"last_score,omitempty"`
+}
+
+type negaMaxJSON struct {
+	Depth    uint8         `json:"depth"`
+	MultiPV  int           `json:"multipv"`
+	Threads  int           `json:"threads"`
+	MaxNodes uint64        `json:"max_nodes"`
+	HashSize int           `json:"hash_mb"`
+	Options  SearchOptions `json:"options"`
+	Strength Strength      `json:"strength"`
+}
+
+type mctsJSON struct {
+	Iterations  int           `json:"iterations"`
+	MoveTime    time.Duration `json:"move_time_ns"`
+	Threads     int           `json:"threads"`
+	Exploration float64       `json:"exploration"`
+}
+
+// MarshalJSON saves the complete state of the game: the moves from its starting position,
+// including the undone ones, the clock, the players with the settings of their AIs and the
+// result. A running search is not saved, the AI starts again when the game is loaded.
+func (g *ChessGame) MarshalJSON() ([]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	result := g.gameResult()
+	j := gameJSON{
+		Version:   saveVersion,
+		Start:     g.history.Start(),
+		Moves:     make([]Move, 0, g.history.Len()),
+		Ply:       g.history.Ply(),
+		Result:    resultJSON{result.Outcome, result.Termination},
+		Pondering: g.ponderEnabled,
+	}
+	for _, e := range g.history.entries {
+		j.Moves = append(j.Moves, e.Move)
+	}
+	if g.drawOffered {
+		by := g.drawOfferBy
+		j.DrawOffer = &by
+	}
+	if g.clock != nil {
+		clock := g.clock.toJSON()
+		j.Clock = &clock
+	}
+	var err error
+	if j.White, err = playerToJSON(g.players[White]); err != nil {
+		return nil, err
+	}
+	if j.Black, err = playerToJSON(g.players[Black]); err != nil {
+		return nil, err
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON loads a game saved by MarshalJSON, replacing the state of g. The clock
+// carries on with the times it showed when the game was saved. A remote player is loaded
+// without a connection, SetPlayer connects it again. The listeners of g are kept.
+func (g *ChessGame) UnmarshalJSON(data []byte) error {
+	var j gameJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	if j.Version != saveVersion {
+		return fmt.Errorf("Unsupported save version: %d", j.Version)
+	}
+	if j.Ply < 0 || j.Ply > len(j.Moves) {
+		return fmt.Errorf("Invalid ply: %d", j.Ply)
+	}
+	if err := j.Start.Validate(); err != nil {
+		return err
+	}
+	history := NewMoveHistory(j.Start)
+	board := j.Start
+	for _, m := range j.Moves {
+		next, err := board.MakeMove(m)
+		if err != nil {
+			return fmt.Errorf("Illegal move in the saved game: %s", m.ToStr())
+		}
+		history.Push(m, board)
+		board = next
+	}
+	for history.Ply() > j.Ply {
+		board, _ = history.Undo(board)
+	}
+
+	// only the results the board does not decide on are kept by the game
+	result := GameResult{j.Result.Outcome, j.Result.Termination}
+	if result.IsOver() != (result.Termination != NotTerminated) {
+		return fmt.Errorf("Invalid result: %s", result.ToStr())
+	}
+	if onBoard := boardResult(&board); onBoard == result {
+		result = GameResult{}
+	} else if onBoard.IsOver() {
+		return fmt.Errorf("The result %s does not match the position.", result.ToStr())
+	}
+
+	var players [2]Player
+	for side, pj := range [2]playerJSON{j.White, j.Black} {
+		player, err := playerFromJSON(pj)
+		if err != nil {
+			return err
+		}
+		players[side] = player
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.cancelAIMove()
+	g.cancelPonder()
+	var clock *Clock
+	if j.Clock != nil {
+		var source ClockSource
+		if g.clock != nil {
+			source = g.clock.source
+		}
+		var err error
+		if clock, err = clockFromJSON(*j.Clock, source); err != nil {
+			return err
+		}
+	}
+	if g.clock != nil {
+		g.clock.Stop()
+	}
+	g.board = board
+	g.players = players
+	g.history = history
+	g.tree = newGameTreeFromHistory(&g.history)
+	g.lastSearch = nil
+	g.ponderEnabled = j.Pondering
+	g.clock = clock
+	g.result = result
+	g.drawOffered = j.DrawOffer != nil
+	if g.drawOffered {
+		g.drawOfferBy = *j.DrawOffer
+	}
+	if g.events == nil {
+		g.events = newEventBus()
+	}
+	return nil
+}
+
+func (c *Clock) toJSON() clockJSON {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	j := clockJSON{
+		Control:    c.control.ToStr(),
+		Remaining:  c.remaining,
+		Stage:      c.stage,
+		StageMoves: c.stageMoves,
+		History:    make([]clockMoveJSON, len(c.history)),
+		Active:     c.active,
+		Running:    c.running,
+		Paused:     c.paused,
+	}
+	if c.running {
+		j.Used = c.turnElapsed()
+	}
+	for i, m := range c.history {
+		j.History[i] = clockMoveJSON{m.side, m.remaining, m.stage, m.stageMoves}
+	}
+	return j
+}
+
+// clockFromJSON restores a saved clock, the turn of the active side carries on from now.
+func clockFromJSON(j clockJSON, source ClockSource) (*Clock, error) {
+	tc, err := ParseTimeControl(j.Control)
+	if err != nil {
+		return nil, err
+	}
+	validStage := func(stage int) bool {
+		return stage >= 0 && stage < len(tc)
+	}
+	c := NewClock(tc, source)
+	for _, side := range []Color{White, Black} {
+		if !validStage(j.Stage[side]) {
+			return nil, fmt.Errorf("Invalid clock stage: %d", j.Stage[side])
+		}
+	}
+	for _, m := range j.History {
+		if !validStage(m.Stage) {
+			return nil, fmt.Errorf("Invalid clock stage: %d", m.Stage)
+		}
+		c.history = append(c.history, clockMove{m.Side, m.Remaining, m.Stage, m.StageMoves})
+	}
+	c.remaining = j.Remaining
+	c.stage = j.Stage
+	c.stageMoves = j.StageMoves
+	c.active = j.Active
+	c.running = j.Running
+	c.paused = j.Paused
+	c.startTurn()
+	c.turnUsed = j.Used
+	return c, nil
+}
+
+func playerToJSON(player Player) (playerJSON, error) {
+	switch p := player.(type) {
+	case *HumanPlayer:
+		return playerJSON{Type: "human", Name: p.PlayerName}, nil
+	case *RemotePlayer:
+		return playerJSON{Type: "remote", Name: p.PlayerName}, nil
+	case *AIPlayer:
+		j := playerJSON{
+			Type:        "ai",
+			Name:        p.PlayerName,
+			ResignCount: p.resignCount,
+			DrawCount:   p.drawCount,
+			LastScore:   p.lastScore,
+		}
+		if p.Adjudication != (Adjudication{}) {
+			adjudication := p.Adjudication
+			j.Adjudication = &adjudication
+		}
+		switch ai := p.AI.(type) {
+		case *NegaMaxAI:
+			j.NegaMax = &negaMaxJSON{
+				Depth:    ai.depth,
+				MultiPV:  ai.MultiPV,
+				Threads:  ai.Threads,
+				MaxNodes: ai.MaxNodes,
+				HashSize: len(ai.tt.entries) * ttEntrySize / (1024 * 1024),
+				Options:  ai.Options,
+				Strength: ai.strength,
+			}
+		case *MCTSAI:
+			j.MCTS = &mctsJSON{ai.Iterations, ai.MoveTime, ai.Threads, ai.Exploration}
+		default:
+			return playerJSON{}, fmt.Errorf("Cannot save an AI of type %T.", p.AI)
+		}
+		return j, nil
+	default:
+		return playerJSON{}, fmt.Errorf("Cannot save a player of type %T.", player)
+	}
+}
+
+func playerFromJSON(j playerJSON) (Player, error) {
+	switch j.Type {
+	case "human":
+		return NewHumanPlayer(j.Name), nil
+	case "remote":
+		return &RemotePlayer{PlayerName: j.Name}, nil
+	case "ai":
+	default:
+		return nil, fmt.Errorf("Invalid player type: %s", j.Type)
+	}
+	var ai AI
+	switch {
+	case j.NegaMax != nil:
+		s := j.NegaMax
+		if s.Depth < 1 || s.Depth > MaxDepth || s.MultiPV < 1 || s.Threads < 1 || s.HashSize < 1 {
+			return nil, fmt.Errorf("Invalid settings of the AI of %s.", j.Name)
+		}
+		nmax := NewNegaMaxAI()
+		nmax.SetDepth(s.Depth)
+		nmax.MultiPV = s.MultiPV
+		nmax.Threads = s.Threads
+		nmax.MaxNodes = s.MaxNodes
+		if s.HashSize != DefaultHashSize {
+			nmax.SetHashSize(s.HashSize)
+		}
+		nmax.Options = s.Options
+		nmax.SetStrength(s.Strength)
+		ai = &nmax
+	case j.MCTS != nil:
+		mcts := NewMCTSAI()
+		mcts.Iterations = j.MCTS.Iterations
+		mcts.MoveTime = j.MCTS.MoveTime
+		mcts.Threads = max(j.MCTS.Threads, 1)
+		mcts.Exploration = j.MCTS.Exploration
+		ai = &mcts
+	default:
+		return nil, fmt.Errorf("The AI of %s has no settings.", j.Name)
+	}
+	player := NewAIPlayer(j.Name, ai)
+	if j.Adjudication != nil {
+		player.Adjudication = *j.Adjudication
+	}
+	player.resignCount = j.ResignCount
+	player.drawCount = j.DrawCount
+	player.lastScore = j.LastScore
+	return player, nil
+}
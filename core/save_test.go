@@ -0,0 +1,371 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+const testTimeControl = "3/300+2:60b1"
+
+// newSaveTestGame returns a timed game between a NegaMaxAI and an MCTSAI with undone
+// moves, a draw offer and the clock of Black running.
+func newSaveTestGame(t *testing.T, source ClockSource) *ChessGame {
+	t.Helper()
+	tc, err := ParseTimeControl(testTimeControl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewGame(WithPlayers(NewHumanPlayer("Alice"), NewHumanPlayer("Bob")),
+		WithTimeControl(tc), WithClockSource(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manual := source.(*ManualClockSource)
+	for i, m := range []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1b5", "a7a6", "b5a4"} {
+		manual.Advance(time.Duration(i+3) * time.Second)
+		board := g.Snapshot()
+		move, err := board.ParseMove(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := g.MakeMove(move.from, move.to, Queen); !ok {
+			t.Fatalf("Move %s was not made.", m)
+		}
+	}
+	manual.Advance(4 * time.Second)
+	g.UndoPreviousMove()
+	g.UndoPreviousMove()
+
+	nmax := NewNegaMaxAI()
+	nmax.SetDepth(4)
+	nmax.MultiPV = 2
+	nmax.Threads = 2
+	nmax.MaxNodes = 100000
+	nmax.SetHashSize(2)
+	nmax.Options.LMR = false
+	nmax.SetStrength(Strength{SkillLevel: 12, Seed: 1 << 60})
+	white := NewAIPlayer("NegaMax", &nmax)
+	white.Adjudication = DefaultAdjudication()
+	white.resignCount, white.drawCount, white.lastScore = 1, 2, -35
+
+	mcts := NewMCTSAI()
+	mcts.Iterations = 5000
+	mcts.MoveTime = 2 * time.Second
+	mcts.Threads = 3
+	mcts.Exploration = 1.25
+	g.SetPlayer(White, white)
+	g.SetPlayer(Black, NewAIPlayer("MCTS", &mcts))
+	g.SetPondering(true)
+
+	if !g.OfferDraw(White) {
+		t.Fatal("The draw offer failed.")
+	}
+	manual.Advance(1500 * time.Millisecond)
+	return g
+}
+
+func newTestClockSource() *ManualClockSource {
+	return NewManualClockSource(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+}
+
+func marshalGame(t *testing.T, g *ChessGame) []byte {
+	t.Helper()
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// loadGame loads data into a new game whose clock uses source.
+func loadGame(t *testing.T, data []byte, source ClockSource) *ChessGame {
+	t.Helper()
+	g, err := NewGame(WithTimeControl(SuddenDeath(time.Minute)), WithClockSource(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, g); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func checkSameGame(t *testing.T, got *ChessGame, want *ChessGame) {
+	t.Helper()
+	if gotBoard, wantBoard := got.Snapshot(), want.Snapshot(); gotBoard != wantBoard {
+		t.Errorf("The position is %s, want %s.", gotBoard.ToFen(), wantBoard.ToFen())
+	}
+	if got.Ply() != want.Ply() || !slices.Equal(got.History(), want.History()) {
+		t.Errorf("The history is %v at ply %d, want %v at ply %d.", got.History(), got.Ply(), want.History(), want.Ply())
+	}
+	if got.Result() != want.Result() {
+		t.Errorf("The result is %s, want %s.", got.Result().ToStr(), want.Result().ToStr())
+	}
+	gotBy, gotOffered := got.DrawOffer()
+	wantBy, wantOffered := want.DrawOffer()
+	if gotBy != wantBy || gotOffered != wantOffered {
+		t.Errorf("The draw offer is %s, %t, want %s, %t.", gotBy.ToStr(), gotOffered, wantBy.ToStr(), wantOffered)
+	}
+	if got.IsPonderingEnabled() != want.IsPonderingEnabled() {
+		t.Errorf("Pondering is %t, want %t.", got.IsPonderingEnabled(), want.IsPonderingEnabled())
+	}
+	gotClock, wantClock := got.Clock(), want.Clock()
+	if gotClock.Control().ToStr() != wantClock.Control().ToStr() || gotClock.Active() != wantClock.Active() ||
+		gotClock.IsRunning() != wantClock.IsRunning() || gotClock.IsPaused() != wantClock.IsPaused() {
+		t.Errorf("The clock is not the same.")
+	}
+	for _, side := range []Color{White, Black} {
+		if got, want := gotClock.Remaining(side), wantClock.Remaining(side); got != want {
+			t.Errorf("%s has %s left, want %s.", side.ToStr(), got, want)
+		}
+		if got, want := gotClock.MovesToGo(side), wantClock.MovesToGo(side); got != want {
+			t.Errorf("%s has %d moves to go, want %d.", side.ToStr(), got, want)
+		}
+	}
+
+	gotWhite, ok := got.Player(White).(*AIPlayer)
+	if !ok {
+		t.Fatalf("White is a %T, want an AIPlayer.", got.Player(White))
+	}
+	wantWhite := want.Player(White).(*AIPlayer)
+	if gotWhite.Name() != wantWhite.Name() || gotWhite.Adjudication != wantWhite.Adjudication ||
+		gotWhite.resignCount != wantWhite.resignCount || gotWhite.drawCount != wantWhite.drawCount ||
+		gotWhite.lastScore != wantWhite.lastScore {
+		t.Errorf("White is %+v, want %+v.", gotWhite, wantWhite)
+	}
+	gotNMax, ok := gotWhite.AI.(*NegaMaxAI)
+	if !ok {
+		t.Fatalf("The AI of White is a %T, want a NegaMaxAI.", gotWhite.AI)
+	}
+	wantNMax := wantWhite.AI.(*NegaMaxAI)
+	if gotNMax.depth != wantNMax.depth || gotNMax.MultiPV != wantNMax.MultiPV || gotNMax.Threads != wantNMax.Threads ||
+		gotNMax.MaxNodes != wantNMax.MaxNodes || len(gotNMax.tt.entries) != len(wantNMax.tt.entries) ||
+		gotNMax.Options != wantNMax.Options || gotNMax.strength != wantNMax.strength {
+		t.Errorf("The AI of White has different settings.")
+	}
+
+	gotBlack, ok := got.Player(Black).(*AIPlayer)
+	if !ok {
+		t.Fatalf("Black is a %T, want an AIPlayer.", got.Player(Black))
+	}
+	gotMCTS, ok := gotBlack.AI.(*MCTSAI)
+	if !ok {
+		t.Fatalf("The AI of Black is a %T, want an MCTSAI.", gotBlack.AI)
+	}
+	wantMCTS := want.Player(Black).(*AIPlayer).AI.(*MCTSAI)
+	if gotBlack.Name() != want.Player(Black).Name() || gotMCTS.Iterations != wantMCTS.Iterations ||
+		gotMCTS.MoveTime != wantMCTS.MoveTime || gotMCTS.Threads != wantMCTS.Threads ||
+		gotMCTS.Exploration != wantMCTS.Exploration {
+		t.Errorf("The AI of Black has different settings.")
+	}
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	source := newTestClockSource()
+	g := newSaveTestGame(t, source)
+	data := marshalGame(t, g)
+	loaded := loadGame(t, data, source)
+	checkSameGame(t, loaded, g)
+	if again := marshalGame(t, loaded); !bytes.Equal(again, data) {
+		t.Errorf("Saving the loaded game gave\n%s\nwant\n%s", again, data)
+	}
+
+	// both games carry on the same way
+	source.Advance(2 * time.Second)
+	for _, game := range []*ChessGame{g, loaded} {
+		if !game.RedoMove() {
+			t.Fatal("The undone move could not be redone.")
+		}
+	}
+	checkSameGame(t, loaded, g)
+	for _, game := range []*ChessGame{g, loaded} {
+		game.UndoPreviousMove()
+		game.PauseClock()
+	}
+	checkSameGame(t, loaded, g)
+	if !bytes.Equal(marshalGame(t, loaded), marshalGame(t, g)) {
+		t.Error("The games differ after the same moves and takebacks.")
+	}
+}
+
+func TestSaveFinishedGame(t *testing.T) {
+	source := newTestClockSource()
+	for name, finish := range map[string]func(g *ChessGame) bool{
+		"resignation": func(g *ChessGame) bool {
+			return g.Resign(Black)
+		},
+		"draw agreement": func(g *ChessGame) bool {
+			return g.AcceptDraw(Black)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			g := newSaveTestGame(t, source)
+			if !finish(g) {
+				t.Fatal("The game could not be finished.")
+			}
+			loaded := loadGame(t, marshalGame(t, g), source)
+			checkSameGame(t, loaded, g)
+			if !loaded.IsGameOver() {
+				t.Error("The loaded game is not over.")
+			}
+		})
+	}
+}
+
+func TestSaveGolden(t *testing.T) {
+	source := newTestClockSource()
+	data := marshalGame(t, newSaveTestGame(t, source))
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, data, "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	indented.WriteByte('\n')
+	path := filepath.Join("testdata", "saved_game.json")
+	if *update {
+		if err := os.WriteFile(path, indented.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(indented.Bytes(), golden) {
+		t.Errorf("The saved game does not match %s, run the tests with -update if the change is intended "+
+			"and increase saveVersion if older saves cannot be loaded any more.\n%s", path, indented.Bytes())
+	}
+
+	// saves of this version have to keep loading
+	loaded := loadGame(t, golden, source)
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, golden); err != nil {
+		t.Fatal(err)
+	}
+	if again := marshalGame(t, loaded); !bytes.Equal(again, compact.Bytes()) {
+		t.Errorf("Saving the golden game after loading it gave\n%s", again)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	source := newTestClockSource()
+	saved := marshalGame(t, newSaveTestGame(t, source))
+	tests := map[string]func(save map[string]any){
+		"version": func(save map[string]any) { save["version"] = 2 },
+		"ply":     func(save map[string]any) { save["ply"] = 100 },
+		"start":   func(save map[string]any) { save["start"] = "8/8/8/8/8/8/8/8 w - - 0 1" },
+		"illegal move": func(save map[string]any) {
+			save["moves"].([]any)[0] = map[string]any{"from": "e2", "to": "e5", "kind": "quiet"}
+		},
+		"result": func(save map[string]any) {
+			save["result"] = map[string]any{"outcome": "1-0", "termination": "unterminated"}
+		},
+		"player type":    func(save map[string]any) { save["white"] = map[string]any{"type": "alien"} },
+		"time control":   func(save map[string]any) { save["clock"].(map[string]any)["control"] = "0" },
+		"clock stage":    func(save map[string]any) { save["clock"].(map[string]any)["stage"] = []int{5, 0} },
+		"draw offer":     func(save map[string]any) { save["draw_offer"] = "red" },
+		"ai settings":    func(save map[string]any) { save["white"].(map[string]any)["negamax"].(map[string]any)["depth"] = 0 },
+		"missing ai":     func(save map[string]any) { delete(save["black"].(map[string]any), "mcts") },
+		"move promotion": func(save map[string]any) { save["moves"].([]any)[0].(map[string]any)["promotion"] = "q" },
+	}
+	for name, corrupt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var save map[string]any
+			if err := json.Unmarshal(saved, &save); err != nil {
+				t.Fatal(err)
+			}
+			corrupt(save)
+			data, err := json.Marshal(save)
+			if err != nil {
+				t.Fatal(err)
+			}
+			g := loadGame(t, saved, source)
+			before := marshalGame(t, g)
+			if err := json.Unmarshal(data, g); err == nil {
+				t.Fatal("The corrupt save was loaded.")
+			}
+			if after := marshalGame(t, g); !bytes.Equal(after, before) {
+				t.Error("Loading the corrupt save changed the game.")
+			}
+		})
+	}
+}
+
+// checkJSON checks that v is written as golden and that golden is read as v.
+func checkJSON[T comparable](t *testing.T, v T, golden string) {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != golden {
+		t.Errorf("%v is written as %s, want %s.", v, data, golden)
+	}
+	var read T
+	if err := json.Unmarshal([]byte(golden), &read); err != nil {
+		t.Fatal(err)
+	}
+	if read != v {
+		t.Errorf("%s is read as %v, want %v.", golden, read, v)
+	}
+}
+
+func TestJSONGolden(t *testing.T) {
+	checkJSON(t, A1, `"a1"`)
+	checkJSON(t, E4, `"e4"`)
+	checkJSON(t, H8, `"h8"`)
+	checkJSON(t, Piece(Nw), `"N"`)
+	checkJSON(t, Piece(Qw), `"Q"`)
+	checkJSON(t, Piece(Pb), `"p"`)
+	checkJSON(t, Piece(Kb), `"k"`)
+	checkJSON(t, White, `"white"`)
+	checkJSON(t, Black, `"black"`)
+	checkJSON(t, WhiteWins, `"1-0"`)
+	checkJSON(t, Draw, `"1/2-1/2"`)
+	checkJSON(t, Ongoing, `"*"`)
+	checkJSON(t, ThreefoldRepetition, `"threefold repetition"`)
+
+	start, err := BoardFromFen(StartFen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkJSON(t, start, `"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"`)
+	const fen = "r3k2r/1P1p4/8/4pP2/8/8/8/R3K2R w KQkq e6 0 30"
+	b, err := BoardFromFen(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkJSON(t, b, `"`+fen+`"`)
+
+	for _, tt := range []struct {
+		move   string
+		golden string
+	}{
+		{"e1f1", `{"from":"e1","to":"f1","kind":"quiet"}`},
+		{"e1g1", `{"from":"e1","to":"g1","kind":"king_castle"}`},
+		{"e1c1", `{"from":"e1","to":"c1","kind":"queen_castle"}`},
+		{"a1a8", `{"from":"a1","to":"a8","kind":"capture"}`},
+		{"f5e6", `{"from":"f5","to":"e6","kind":"en_passant"}`},
+		{"b7b8q", `{"from":"b7","to":"b8","kind":"promotion","promotion":"q"}`},
+		{"b7b8n", `{"from":"b7","to":"b8","kind":"promotion","promotion":"n"}`},
+		{"b7a8r", `{"from":"b7","to":"a8","kind":"promotion_capture","promotion":"r"}`},
+	} {
+		m, err := b.ParseMove(tt.move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkJSON(t, m, tt.golden)
+	}
+	m, err := start.ParseMove("e2e4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkJSON(t, m, `{"from":"e2","to":"e4","kind":"double_pawn_push"}`)
+}
@@ -0,0 +1,332 @@
+package core
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync/atomic"
+)
+
+// maxPly is the deepest ply the search will ever reach, including extensions.
+const maxPly = 128
+
+// lmrTable holds the late move reduction for a given depth and move number.
+var lmrTable [64][64]int
+
+// futilityMargin is indexed by the remaining depth.
+var futilityMargin = [...]int32{0, 200, 450}
+
+const reverseFutilityMargin int32 = 120
+
+func init() {
+	for depth := 1; depth < 64; depth++ {
+		for moveNumber := 1; moveNumber < 64; moveNumber++ {
+			lmrTable[depth][moveNumber] = int(0.75 + math.Log(float64(depth))*math.Log(float64(moveNumber))/2.25)
+		}
+	}
+}
+
+// searcher holds the state of a single search thread.
+type searcher struct {
+	ai *NegaMaxAI
+	// id is 0 for the main thread and greater than 0 for the helper threads.
+	id int
+	tt *transpositionTable
+	// stop is shared between all the threads of a search.
+	// Only the main thread watches ctx and sets stop once canStop is set.
+	stop     *atomic.Bool
+	ctx      context.Context
+	canStop  bool
+	checks   uint
+	maxNodes uint64
+	nodes    atomic.Uint64
+	selDepth int
+	// the statistics counters are only read once the thread has finished
+	qnodes           uint64
+	betaCutoffs      uint64
+	firstMoveCutoffs uint64
+	// pvTable is a triangular table, the principal variation from ply i is
+	// stored in pvTable[i][i:pvLength[i]]
+	pvTable  [maxPly + 1][maxPly + 1]Move
+	pvLength [maxPly + 1]int
+	// prevPV is the principal variation of the previous iteration.
+	// It is searched first while followPV is set.
+	prevPV   []Move
+	followPV bool
+	// excluded root moves are skipped, they have already been searched by an earlier MultiPV line.
+	excluded []Move
+}
+
+func newSearcher(ai *NegaMaxAI, id int, stop *atomic.Bool) *searcher {
+	return &searcher{
+		ai:   ai,
+		id:   id,
+		tt:   ai.tt,
+		stop: stop,
+	}
+}
+
+// helperSearch keeps deepening the search of b until it is stopped.
+// Helper threads start at alternating depths and order the root moves differently
+// so that they do not all search the same tree, their results are shared with the
+// main thread only through the transposition table.
+func (s *searcher) helperSearch(b Board) {
+	for depth := 1 + s.id%2; depth < maxPly && !s.stop.Load(); depth++ {
+		s.negamax(b, depth, 0, MinScore, MaxScore, false)
+	}
+}
+
+// updatePV makes move followed by the principal variation of the child node
+// the principal variation at ply.
+func (s *searcher) updatePV(ply int, move Move) {
+	s.pvTable[ply][ply] = move
+	copy(s.pvTable[ply][ply+1:], s.pvTable[ply+1][ply+1:s.pvLength[ply+1]])
+	s.pvLength[ply] = s.pvLength[ply+1]
+}
+
+// shouldStop reports whether the search has to be abandoned.
+func (s *searcher) shouldStop() bool {
+	if s.id == 0 && s.canStop {
+		if s.maxNodes > 0 && s.nodes.Load() >= s.maxNodes {
+			s.stop.Store(true)
+		}
+		s.checks += 1
+		if s.ctx != nil && s.checks%1024 == 0 && s.ctx.Err() != nil {
+			s.stop.Store(true)
+		}
+	}
+	return s.stop.Load()
+}
+
+func (s *searcher) negamax(b Board, depth int, ply int, alpha int32, beta int32, nullAllowed bool) int32 {
+	if ply == 0 {
+		s.followPV = true
+	}
+	if depth <= 0 || ply >= maxPly {
+		return s.quiescence(b, ply, alpha, beta)
+	}
+	s.nodes.Add(1)
+	s.pvLength[ply] = ply
+	s.selDepth = max(s.selDepth, ply)
+	if s.shouldStop() {
+		return 0
+	}
+	opts := &s.ai.Options
+	inCheck := b.isActiveSideInCheck()
+	pvNode := beta-alpha > 1
+	alphaOrig := alpha
+
+	ttEntry, ttHit := s.tt.probe(b.hash, ply)
+	if ttHit && ply > 0 && !pvNode && ttEntry.depth >= depth {
+		switch {
+		case ttEntry.bound == ttExact,
+			ttEntry.bound == ttLower && ttEntry.score >= beta,
+			ttEntry.bound == ttUpper && ttEntry.score <= alpha:
+			return ttEntry.score
+		}
+	}
+
+	futilityPrune := false
+	if !pvNode && !inCheck {
+		staticEval := s.ai.relativeEval(&b)
+		if opts.ReverseFutility && depth <= 3 && staticEval-reverseFutilityMargin*int32(depth) >= beta {
+			return staticEval
+		}
+		if opts.NullMove && nullAllowed && depth >= 3 && staticEval >= beta && b.hasNonPawnMaterial(b.activeColor) {
+			reduction := 2 + depth/4
+			score := -s.negamax(b.makeNullMove(), depth-1-reduction, ply+1, -beta, -beta+1, false)
+			if score >= beta {
+				// mate scores from a null move search can not be trusted
+				if isMateScore(score) {
+					return beta
+				}
+				return score
+			}
+		}
+		if opts.Futility && depth < len(futilityMargin) && staticEval+futilityMargin[depth] <= alpha {
+			futilityPrune = true
+		}
+	}
+
+	moves := orderMoves(&b, b.getAllLegalMoves(b.activeColor))
+	if ttHit {
+		promoteMove(moves, ttEntry.move)
+	}
+	if s.followPV {
+		if ply < len(s.prevPV) {
+			promoteMove(moves, s.prevPV[ply])
+		} else {
+			s.followPV = false
+		}
+	}
+	if ply == 0 && s.id > 0 && len(moves) > 2 {
+		// helper threads rotate the root moves after the best one
+		shift := s.id % (len(moves) - 1)
+		slices.Reverse(moves[1 : 1+shift])
+		slices.Reverse(moves[1+shift:])
+		slices.Reverse(moves[1:])
+	}
+
+	value := MinScore
+	var bestMove Move
+	movesSearched := 0
+	for _, move := range moves {
+		if ply == 0 && slices.Contains(s.excluded, move) {
+			continue
+		}
+		board_copy, ok := b.makeMove(move)
+		if !ok {
+			continue
+		}
+		givesCheck := board_copy.isActiveSideInCheck()
+		quiet := !move.IsCapture() && !move.IsPromotion()
+		if futilityPrune && movesSearched > 0 && quiet && !givesCheck {
+			continue
+		}
+
+		newDepth := depth - 1
+		if opts.CheckExtensions && givesCheck {
+			newDepth += 1
+		}
+
+		var score int32
+		if movesSearched == 0 {
+			score = -s.negamax(board_copy, newDepth, ply+1, -beta, -alpha, true)
+			s.followPV = false
+		} else {
+			reduction := 0
+			if opts.LMR && depth >= 3 && movesSearched >= 3 && quiet && !inCheck && !givesCheck {
+				reduction = lmrTable[min(depth, 63)][min(movesSearched, 63)]
+			}
+			windowAlpha := -beta
+			if opts.PVS {
+				windowAlpha = -alpha - 1
+			}
+			score = -s.negamax(board_copy, newDepth-reduction, ply+1, windowAlpha, -alpha, true)
+			if reduction > 0 && score > alpha {
+				score = -s.negamax(board_copy, newDepth, ply+1, windowAlpha, -alpha, true)
+			}
+			if opts.PVS && score > alpha && score < beta {
+				score = -s.negamax(board_copy, newDepth, ply+1, -beta, -alpha, true)
+			}
+		}
+		movesSearched += 1
+
+		if score > value {
+			value = score
+			bestMove = move
+			if score > alpha {
+				s.updatePV(ply, move)
+			}
+		}
+		alpha = max(alpha, value)
+		if alpha >= beta {
+			s.betaCutoffs += 1
+			if movesSearched == 1 {
+				s.firstMoveCutoffs += 1
+			}
+			break
+		}
+	}
+
+	if movesSearched == 0 {
+		if inCheck {
+			return MatingScore + int32(ply)
+		}
+		// stalemate
+		return 0
+	}
+	if s.stop.Load() {
+		// the search was interrupted, value can not be trusted
+		return 0
+	}
+	if ply > 0 || len(s.excluded) == 0 {
+		bound := ttExact
+		if value >= beta {
+			bound = ttLower
+		} else if value <= alphaOrig {
+			bound = ttUpper
+		}
+		s.tt.store(b.hash, bestMove, value, depth, bound, ply)
+	}
+	return value
+}
+
+// quiescence extends the search at the leaves with captures only, so that the
+// static evaluation is never taken in the middle of an exchange.
+// Captures which lose material according to SEE are skipped.
+func (s *searcher) quiescence(b Board, ply int, alpha int32, beta int32) int32 {
+	s.nodes.Add(1)
+	s.qnodes += 1
+	s.selDepth = max(s.selDepth, ply)
+	s.pvLength[ply] = ply
+	if s.shouldStop() {
+		return 0
+	}
+	standPat := s.ai.relativeEval(&b)
+	if ply >= maxPly {
+		return standPat
+	}
+	if standPat >= beta {
+		return standPat
+	}
+	alpha = max(alpha, standPat)
+	for _, move := range orderMoves(&b, b.getAllLegalMoves(b.activeColor)) {
+		if !move.IsCapture() {
+			// captures are ordered first
+			break
+		}
+		if !b.SEEGe(move, 0) {
+			continue
+		}
+		if board_copy, ok := b.makeMove(move); ok {
+			score := -s.quiescence(board_copy, ply+1, -beta, -alpha)
+			if score >= beta {
+				return score
+			}
+			alpha = max(alpha, score)
+		}
+	}
+	return alpha
+}
+
+// relativeEval returns the evaluation of the board from the point of view of the active side.
+func (nmax *NegaMaxAI) relativeEval(b *Board) int32 {
+	score := nmax.evalMethod(b)
+	if nmax.strength.isLimited() {
+		score += nmax.strength.noise(b.hash)
+	}
+	if b.activeColor == Black {
+		return -score
+	}
+	return score
+}
+
+// orderMoves sorts moves so that captures come first, ordered by their SEE value.
+// Quiet moves keep the order in which they were generated.
+func orderMoves(b *Board, moves MoveList) MoveList {
+	see := make(map[Move]int32, len(moves))
+	for _, move := range moves {
+		if move.IsCapture() {
+			see[move] = b.SEE(move)
+		}
+	}
+	slices.SortStableFunc(moves, func(m1 Move, m2 Move) int {
+		if m1.IsCapture() != m2.IsCapture() {
+			if m1.IsCapture() {
+				return -1
+			}
+			return 1
+		}
+		return int(see[m2] - see[m1])
+	})
+	return moves
+}
+
+// promoteMove moves m to the front of moves if it is present.
+func promoteMove(moves MoveList, m Move) {
+	if i := slices.Index(moves, m); i > 0 {
+		copy(moves[1:i+1], moves[:i])
+		moves[0] = m
+	}
+}
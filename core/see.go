@@ -0,0 +1,109 @@
+package core
+
+// seeValue returns the material value of a piece used by the static exchange evaluation.
+// Colors are ignored, a white and a black rook are worth the same.
+func seeValue(p Piece) int32 {
+	return PieceScore[p%6]
+}
+
+// attackersTo returns a BitBoard of all the pieces of both colors which attack sq
+// given the occupancy occ. Sliding attacks are looked up from the magic tables with occ
+// as the blockers so that x-ray attackers are revealed as pieces are removed from occ.
+func (b *Board) attackersTo(sq Square, occ BitBoard) BitBoard {
+	bishops := b.bitBoards[Bw] | b.bitBoards[Bb] | b.bitBoards[Qw] | b.bitBoards[Qb]
+	rooks := b.bitBoards[Rw] | b.bitBoards[Rb] | b.bitBoards[Qw] | b.bitBoards[Qb]
+	attackers := (PawnAtkTable[Black][sq] & b.bitBoards[Pw]) |
+		(PawnAtkTable[White][sq] & b.bitBoards[Pb]) |
+		(KnightAtkTable[sq] & (b.bitBoards[Nw] | b.bitBoards[Nb])) |
+		(KingAtkTable[sq] & (b.bitBoards[Kw] | b.bitBoards[Kb])) |
+		(GetBishopMoves(sq, occ) & bishops) |
+		(GetRookMoves(sq, occ) & rooks)
+	return attackers & occ
+}
+
+// leastValuableAttacker picks the cheapest piece of color c from attackers.
+func (b *Board) leastValuableAttacker(attackers BitBoard, c Color) (Square, Piece, bool) {
+	pieces := WhitePieces
+	if c == Black {
+		pieces = BlackPieces
+	}
+	for _, piece := range pieces {
+		if sq, ok := (attackers & b.bitBoards[piece]).Peek(); ok {
+			return sq, piece, true
+		}
+	}
+	return 0, 0, false
+}
+
+// SEE statically evaluates the sequence of captures on the destination square of m.
+// Both sides always recapture with their least valuable attacker and may stop capturing
+// when it is not profitable. The result is the material balance from the point of view
+// of the side making the move, positive values mean that the capture wins material.
+// Quiet moves are evaluated as well, they return a negative value if the moved piece is lost.
+func (b *Board) SEE(m Move) int32 {
+	moving_piece, occupied := b.GetAtSq(m.from)
+	if !occupied {
+		return 0
+	}
+
+	occ := b.whiteOccupancy() | b.blackOccupancy()
+	var gain [32]int32
+	if m.IsEp() {
+		captured_square := Square(m.to - 8)
+		if moving_piece == Pb {
+			captured_square = Square(m.to + 8)
+		}
+		occ = occ.UnSet(captured_square)
+		gain[0] = seeValue(Pw)
+	} else if captured_piece, occupied := b.GetAtSq(m.to); occupied {
+		gain[0] = seeValue(captured_piece)
+	}
+
+	// value of the piece which is standing on the square after each capture
+	onSquare := seeValue(moving_piece)
+	if m.IsPromotion() {
+		promoted_piece := m.GetPromPiece().WithColor(White)
+		gain[0] += seeValue(promoted_piece) - seeValue(Pw)
+		onSquare = seeValue(promoted_piece)
+	}
+
+	side := moving_piece.GetColor()
+	fromSq := m.from
+	d := 0
+	for {
+		d++
+		gain[d] = onSquare - gain[d-1]
+		if max(-gain[d-1], gain[d]) < 0 {
+			// neither side can improve the result by continuing
+			break
+		}
+		occ = occ.UnSet(fromSq)
+		side ^= 1
+		attackers := b.attackersTo(m.to, occ)
+		sq, piece, ok := b.leastValuableAttacker(attackers&b.getColorOccupancy(side), side)
+		if !ok {
+			break
+		}
+		if piece == Kw || piece == Kb {
+			// the king can only recapture if the square is no longer defended
+			if b.attackersTo(m.to, occ.UnSet(sq))&b.getColorOccupancy(side^1) > 0 {
+				break
+			}
+		}
+		onSquare = seeValue(piece)
+		fromSq = sq
+		if d == len(gain)-1 {
+			break
+		}
+	}
+
+	for d--; d > 0; d-- {
+		gain[d-1] = -max(-gain[d-1], gain[d])
+	}
+	return gain[0]
+}
+
+// SEEGe reports whether the static exchange evaluation of m is at least threshold.
+func (b *Board) SEEGe(m Move, threshold int32) bool {
+	return b.SEE(m) >= threshold
+}
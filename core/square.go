@@ -1,10 +1,6 @@
 package core
 
-import (
-	"errors"
-	"fmt"
-	u "unicode"
-)
+import "fmt"
 
 type Square uint8
 
@@ -13,29 +9,10 @@ func SquareFromXY(x int, y int) Square {
 }
 
 func StrToSq(s string) (Square, error) {
-	if len(s) > 2 {
-		return 0, errors.New("Invalid square. 1")
-	}
-	var x uint8
-	var y uint8
-
-	if u.IsLetter(rune(s[0])) {
-		x = uint8(rune(s[0]) - 'a')
-	} else {
-		return 0, errors.New("Invalid square.")
-	}
-
-	if u.IsDigit(rune(s[1])) {
-		y = uint8(rune(s[1]) - '1')
-	} else {
-		return 0, errors.New("Invalid square.")
-	}
-
-	sq := Square(y*8 + x)
-	if sq >= 64 {
-		return 0, errors.New("Invalid square.")
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return 0, fmt.Errorf("Invalid square: %s", s)
 	}
-	return sq, nil
+	return SquareFromXY(int(s[0]-'a'), int(s[1]-'1')), nil
 }
 
 func (sq Square) ToStr() string {
@@ -56,6 +33,23 @@ func (sq Square) ToXY() (uint8, uint8) {
 	return uint8(x), uint8(y)
 }
 
+// MarshalText writes the square as in algebraic notation, e.g. e4.
+func (sq Square) MarshalText() ([]byte, error) {
+	if sq >= 64 {
+		return nil, fmt.Errorf("Invalid square: %d", sq)
+	}
+	return []byte(sq.ToStr()), nil
+}
+
+func (sq *Square) UnmarshalText(text []byte) error {
+	parsed, err := StrToSq(string(text))
+	if err != nil {
+		return err
+	}
+	*sq = parsed
+	return nil
+}
+
 const (
 	A1 Square = iota
 	B1
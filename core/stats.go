@@ -0,0 +1,80 @@
+package core
+
+import (
+	"log/slog"
+	"time"
+)
+
+// SearchStats describes how a NegaMaxAI search went. The counters sum up all the threads.
+type SearchStats struct {
+	Nodes uint64 `json:"nodes"`
+	// QNodes are the nodes searched by the quiescence search, they are part of Nodes.
+	QNodes      uint64 `json:"qnodes"`
+	BetaCutoffs uint64 `json:"beta_cutoffs"`
+	// FirstMoveCutoffs are the beta cutoffs caused by the first move searched.
+	// The higher their share of BetaCutoffs the better the move ordering.
+	FirstMoveCutoffs uint64           `json:"first_move_cutoffs"`
+	Iterations       []IterationStats `json:"iterations"`
+	Time             time.Duration    `json:"time_ns"`
+}
+
+// IterationStats describes a single iteration of the iterative deepening.
+type IterationStats struct {
+	Depth int `json:"depth"`
+	// Nodes is the number of nodes searched by this iteration alone.
+	Nodes uint64 `json:"nodes"`
+	// EBF is the effective branching factor, the ratio between the nodes of this
+	// iteration and of the previous one. It is 0 for the first iteration.
+	EBF  float64       `json:"ebf"`
+	Time time.Duration `json:"time_ns"`
+}
+
+// FirstMoveCutoffRate returns the share of the beta cutoffs caused by the first move.
+func (s *SearchStats) FirstMoveCutoffRate() float64 {
+	if s.BetaCutoffs == 0 {
+		return 0
+	}
+	return float64(s.FirstMoveCutoffs) / float64(s.BetaCutoffs)
+}
+
+// addIteration records an iteration which ended after nodes nodes and elapsed time in total.
+func (s *SearchStats) addIteration(depth int, nodes uint64, elapsed time.Duration) {
+	iteration := IterationStats{Depth: depth, Nodes: nodes, Time: elapsed}
+	if n := len(s.Iterations); n > 0 {
+		var prevNodes uint64
+		var prevTime time.Duration
+		for _, it := range s.Iterations {
+			prevNodes += it.Nodes
+			prevTime += it.Time
+		}
+		iteration.Nodes -= prevNodes
+		iteration.Time -= prevTime
+		if s.Iterations[n-1].Nodes > 0 {
+			iteration.EBF = float64(iteration.Nodes) / float64(s.Iterations[n-1].Nodes)
+		}
+	}
+	s.Iterations = append(s.Iterations, iteration)
+}
+
+// collect adds up the counters of the threads of a search.
+func (s *SearchStats) collect(elapsed time.Duration, threads ...*searcher) {
+	s.Time = elapsed
+	s.Nodes, s.QNodes, s.BetaCutoffs, s.FirstMoveCutoffs = 0, 0, 0, 0
+	for _, t := range threads {
+		s.Nodes += t.nodes.Load()
+		s.QNodes += t.qnodes
+		s.BetaCutoffs += t.betaCutoffs
+		s.FirstMoveCutoffs += t.firstMoveCutoffs
+	}
+}
+
+func (s *SearchStats) log() {
+	attrs := []any{
+		"nodes", s.Nodes, "qnodes", s.QNodes, "beta_cutoffs", s.BetaCutoffs,
+		"first_move_cutoff_rate", s.FirstMoveCutoffRate(), "time", s.Time,
+	}
+	if n := len(s.Iterations); n > 0 {
+		attrs = append(attrs, "ebf", s.Iterations[n-1].EBF)
+	}
+	slog.Debug("Search statistics.", attrs...)
+}
@@ -0,0 +1,87 @@
+package core
+
+import "github.com/ParthPant/gochess/util"
+
+// MaxSkillLevel is full strength. Lower skill levels limit the depth and the number
+// of nodes of the search, add noise to the evaluation and make the AI pick weaker moves.
+const MaxSkillLevel = 20
+
+// The range of Elo ratings which StrengthFromElo maps onto the skill levels.
+const (
+	MinElo = 1000
+	MaxElo = 2400
+)
+
+const defaultStrengthSeed uint64 = 0x9e3779b97f4a7c15
+
+// skillMultiPV is the number of lines searched to choose a weaker move from.
+const skillMultiPV = 4
+
+// Strength limits how well the NegaMaxAI plays.
+type Strength struct {
+	// SkillLevel goes from 0, the weakest, to MaxSkillLevel.
+	SkillLevel int `json:"skill_level"`
+	// Seed makes the evaluation noise and the choice of weaker moves reproducible.
+	Seed uint64 `json:"seed,string"`
+}
+
+func FullStrength() Strength {
+	return Strength{MaxSkillLevel, defaultStrengthSeed}
+}
+
+func NewStrength(skillLevel int) Strength {
+	return Strength{min(max(skillLevel, 0), MaxSkillLevel), defaultStrengthSeed}
+}
+
+// StrengthFromElo returns the skill level which plays closest to the given Elo rating.
+// Ratings outside of MinElo and MaxElo are clamped.
+func StrengthFromElo(elo int) Strength {
+	elo = min(max(elo, MinElo), MaxElo)
+	return NewStrength((elo - MinElo) * MaxSkillLevel / (MaxElo - MinElo))
+}
+
+func (s Strength) isLimited() bool {
+	return s.SkillLevel < MaxSkillLevel
+}
+
+func (s Strength) depthLimit() int {
+	return 1 + s.SkillLevel/4
+}
+
+func (s Strength) nodeLimit() uint64 {
+	return 200 * uint64(s.SkillLevel+1) * uint64(s.SkillLevel+1)
+}
+
+// noise returns an offset to the evaluation of the position with the given hash.
+// It only depends on the hash and the seed so that a position always gets the same noise.
+func (s Strength) noise(hash uint64) int32 {
+	amplitude := int32(MaxSkillLevel-s.SkillLevel) * 8
+	if amplitude <= 0 {
+		return 0
+	}
+	var prng util.PRNG
+	prng.Seed(hash ^ s.Seed | 1)
+	return int32(prng.Rand64()%uint64(2*amplitude+1)) - amplitude
+}
+
+// pickLine chooses one of the lines of a MultiPV search. The lower the skill level the
+// more likely it is to choose a line which scores worse than the best one.
+// lines must be sorted from the best to the worst.
+func (s Strength) pickLine(lines []PVLine, prng *util.PRNG) PVLine {
+	best := lines[0]
+	delta := min(lines[0].Score-lines[len(lines)-1].Score, seeValue(Pw))
+	weakness := int32(120 - 2*s.SkillLevel)
+	maxScore := MinScore
+	for _, line := range lines {
+		if isMateScore(line.Score) && line.Score > 0 {
+			// never miss a mate
+			return line
+		}
+		push := (weakness*(lines[0].Score-line.Score) + delta*int32(prng.Rand64()%uint64(weakness))) / 128
+		if line.Score+push >= maxScore {
+			maxScore = line.Score + push
+			best = line
+		}
+	}
+	return best
+}
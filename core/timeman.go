@@ -0,0 +1,22 @@
+package core
+
+import "time"
+
+// defaultMovesToGo is the number of moves the remaining time is divided into
+// when the time control does not say how many moves are left.
+const defaultMovesToGo = 30
+
+// moveOverhead is kept in reserve to account for the time lost communicating the move.
+const moveOverhead = 50 * time.Millisecond
+
+// TimeBudget returns how long the AI should think about its move given the time left
+// on its clock, the increment it receives after the move and the number of moves until
+// the next time control. movesToGo is 0 for sudden death time controls.
+func TimeBudget(remaining time.Duration, increment time.Duration, movesToGo int) time.Duration {
+	if movesToGo <= 0 {
+		movesToGo = defaultMovesToGo
+	}
+	budget := remaining/time.Duration(movesToGo) + increment*3/4
+	budget = min(budget, remaining-moveOverhead)
+	return max(budget, time.Millisecond)
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+type ttBound uint8
+
+const (
+	ttExact ttBound = iota
+	ttLower
+	ttUpper
+)
+
+const ttEntrySize = 16
+
+// DefaultHashSize is the size of the transposition table in MB.
+const DefaultHashSize = 16
+
+// ttEntry stores the zobrist hash xored with the data, so that an entry which was
+// torn by two threads writing it at the same time is detected when it is probed.
+// This keeps the table lock-free while it is shared between search threads.
+type ttEntry struct {
+	key  atomic.Uint64
+	data atomic.Uint64
+}
+
+type ttData struct {
+	move  Move
+	score int32
+	depth int
+	bound ttBound
+}
+
+type transpositionTable struct {
+	entries []ttEntry
+	mask    uint64
+}
+
+// newTranspositionTable allocates a table of at most sizeMB megabytes.
+// The number of entries is rounded down to a power of two.
+func newTranspositionTable(sizeMB int) *transpositionTable {
+	count := uint64(max(sizeMB, 1)) * 1024 * 1024 / ttEntrySize
+	count = 1 << (63 - bits.LeadingZeros64(count))
+	return &transpositionTable{
+		entries: make([]ttEntry, count),
+		mask:    count - 1,
+	}
+}
+
+func (tt *transpositionTable) clear() {
+	for i := range tt.entries {
+		tt.entries[i].key.Store(0)
+		tt.entries[i].data.Store(0)
+	}
+}
+
+func (tt *transpositionTable) probe(hash uint64, ply int) (ttData, bool) {
+	entry := &tt.entries[hash&tt.mask]
+	data := entry.data.Load()
+	if entry.key.Load()^data != hash || data == 0 {
+		return ttData{}, false
+	}
+	return ttData{
+		move:  unpackMove(uint16(data)),
+		score: scoreFromTT(int32(uint32(data>>16)), ply),
+		depth: int(uint8(data >> 48)),
+		bound: ttBound(data >> 56),
+	}, true
+}
+
+func (tt *transpositionTable) store(hash uint64, move Move, score int32, depth int, bound ttBound, ply int) {
+	entry := &tt.entries[hash&tt.mask]
+	data := uint64(packMove(move)) |
+		uint64(uint32(scoreToTT(score, ply)))<<16 |
+		uint64(uint8(depth))<<48 |
+		uint64(bound)<<56
+	entry.key.Store(hash ^ data)
+	entry.data.Store(data)
+}
+
+// scoreToTT converts mate scores from being relative to the root to being
+// relative to the current node, so that they stay correct when probed at a different ply.
+func scoreToTT(score int32, ply int) int32 {
+	if score >= -MatingScore-maxPly {
+		return score + int32(ply)
+	} else if score <= MatingScore+maxPly {
+		return score - int32(ply)
+	}
+	return score
+}
+
+func scoreFromTT(score int32, ply int) int32 {
+	if score >= -MatingScore-maxPly {
+		return score - int32(ply)
+	} else if score <= MatingScore+maxPly {
+		return score + int32(ply)
+	}
+	return score
+}
+
+func packMove(m Move) uint16 {
+	return uint16(m.from) | uint16(m.to)<<6 | uint16(m.flags)<<12
+}
+
+func unpackMove(p uint16) Move {
+	return Move{
+		flags: uint8(p >> 12),
+		from:  Square(p & 0x3f),
+		to:    Square((p >> 6) & 0x3f),
+	}
+}
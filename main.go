@@ -1,16 +1,87 @@
 package main
 
 import (
+	"flag"
 	"log/slog"
 	"os"
+	"runtime"
 
 	"github.com/ParthPant/gochess/core"
+	"github.com/ParthPant/gochess/uci"
 	"github.com/ParthPant/gochess/ui"
 )
 
 func main() {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
 	slog.SetDefault(logger)
-	g := ui.CreateGui(core.NewGame(core.White), 800)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "analyse":
+			os.Exit(runAnalyse(os.Args[2:]))
+		case "bench":
+			os.Exit(runBench(os.Args[2:]))
+		case "mate":
+			os.Exit(runMate(os.Args[2:]))
+		case "review":
+			os.Exit(runReview(os.Args[2:]))
+		case "uci":
+			if err := uci.NewEngine(os.Stdout).Run(os.Stdin); err != nil {
+				slog.Error("UCI engine failed.", "error", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	aiName := flag.String("ai", "negamax", "the AI to play against, negamax or mcts")
+	white := flag.String("white", "human", "who plays White, human or ai")
+	black := flag.String("black", "ai", "who plays Black, human or ai")
+	tc := flag.String("tc", "", "time control as in the PGN TimeControl tag, e.g. 300+2 or 40/5400+30:1800+30")
+	fen := flag.String("fen", core.StartFen, "the position to start the game from")
+	savePath := flag.String("save", "gochess.json", "the file F5 saves the game to and F9 loads it from")
+	flag.Parse()
+	opts := []core.GameOption{core.WithFEN(*fen)}
+	if *tc != "" {
+		control, err := core.ParseTimeControl(*tc)
+		if err != nil {
+			slog.Error("Invalid time control.", "error", err)
+			os.Exit(2)
+		}
+		opts = append(opts, core.WithTimeControl(control))
+	}
+	var players [2]core.Player
+	for i, kind := range []string{*white, *black} {
+		switch kind {
+		case "human":
+			players[i] = core.NewHumanPlayer("")
+		case "ai":
+			switch *aiName {
+			case "negamax":
+				ai := core.NewNegaMaxAI()
+				ai.Threads = runtime.NumCPU()
+				players[i] = core.NewAIPlayer("NegaMax", &ai)
+			case "mcts":
+				ai := core.NewMCTSAI()
+				players[i] = core.NewAIPlayer("MCTS", &ai)
+			default:
+				slog.Error("Unknown AI.", "ai", *aiName)
+				os.Exit(2)
+			}
+		default:
+			slog.Error("Unknown player.", "player", kind)
+			os.Exit(2)
+		}
+	}
+	for _, player := range players {
+		if ai, ok := player.(*core.AIPlayer); ok {
+			ai.Adjudication = core.DefaultAdjudication()
+		}
+	}
+	opts = append(opts, core.WithPlayers(players[core.White], players[core.Black]))
+	game, err := core.NewGame(opts...)
+	if err != nil {
+		slog.Error("Cannot start the game.", "error", err)
+		os.Exit(2)
+	}
+	g := ui.CreateGui(game, 800, *savePath)
 	g.GameLoop()
 }
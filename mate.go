@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ParthPant/gochess/core"
+)
+
+// runMate verifies mate in n problems. The FENs are read one per line from stdin
+// unless a FEN is given as arguments.
+// Usage: gochess mate [-n moves] [-tree] [-timeout d] [fen]
+func runMate(args []string) int {
+	fs := flag.NewFlagSet("mate", flag.ExitOnError)
+	n := fs.Int("n", 2, "number of moves to mate in")
+	showTree := fs.Bool("tree", false, "print the full mating tree")
+	timeout := fs.Duration("timeout", 0, "time limit per problem, 0 means no limit")
+	fs.Parse(args)
+
+	var fens []string
+	if fs.NArg() > 0 {
+		fens = append(fens, strings.Join(fs.Args(), " "))
+	} else {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if fen := strings.TrimSpace(scanner.Text()); fen != "" && !strings.HasPrefix(fen, "#") {
+				fens = append(fens, fen)
+			}
+		}
+	}
+
+	failed := 0
+	for _, fen := range fens {
+		if !solveMate(os.Stdout, fen, *n, *showTree, *timeout) {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// solveMate prints the solution of a single problem and returns false if it is unsound,
+// which is when it has no solution or more than one.
+func solveMate(w io.Writer, fen string, n int, showTree bool, timeout time.Duration) bool {
+	fmt.Fprintln(w, fen)
+	board, err := core.BoardFromFen(fen)
+	if err != nil {
+		fmt.Fprintf(w, "  error: %s\n", err)
+		return false
+	}
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+	solution, found, err := core.FindMateContext(ctx, &board, n)
+	if err != nil {
+		fmt.Fprintf(w, "  error: %s\n", err)
+		return false
+	}
+	if !found {
+		fmt.Fprintf(w, "  no mate in %d (%d nodes)\n", n, solution.Nodes)
+		return false
+	}
+	fmt.Fprintf(w, "  mate in %d: %s (%d nodes)\n", solution.Tree.Depth, solution.Tree.LineToStr(), solution.Nodes)
+	if showTree {
+		printMateTree(w, solution.Tree, 2)
+	}
+	for _, cook := range solution.Cooks {
+		fmt.Fprintf(w, "  cook, mate in %d: %s\n", cook.Depth, cook.LineToStr())
+	}
+	return len(solution.Cooks) == 0
+}
+
+func printMateTree(w io.Writer, tree core.MateTree, indent int) {
+	fmt.Fprintf(w, "%s%s\n", strings.Repeat(" ", indent), tree.Move.ToStr())
+	for _, defence := range tree.Defences {
+		fmt.Fprintf(w, "%s... %s\n", strings.Repeat(" ", indent+2), defence.Move.ToStr())
+		printMateTree(w, defence.Mate, indent+4)
+	}
+}
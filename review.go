@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ParthPant/gochess/core"
+)
+
+// runReview analyses a game saved by the GUI, prints how well each side played and
+// writes the game annotated with the mistakes and the better moves as PGN.
+// Usage: gochess review [-depth n] [-movetime d] [-threads n] [-pgn file] game.json
+func runReview(args []string) int {
+	fs := flag.NewFlagSet("review", flag.ExitOnError)
+	depth := fs.Uint("depth", uint(core.DefaultAnalysisDepth), "depth to search each position to, 0 to only use -movetime")
+	moveTime := fs.Duration("movetime", 0, "time limit per position, 0 means no limit")
+	threads := fs.Int("threads", 0, "number of search threads, 0 uses all CPUs")
+	pgnPath := fs.String("pgn", "", "file to write the annotated PGN to instead of stdout")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: gochess review [flags] game.json")
+		return 2
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var game core.ChessGame
+	if err := json.Unmarshal(data, &game); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	analyser := core.NewAnalyser()
+	analyser.Depth = uint8(*depth)
+	analyser.MoveTime = *moveTime
+	if *threads > 0 {
+		analyser.Threads = *threads
+	}
+	analyser.Progress = func(done int, total int) {
+		fmt.Fprintf(os.Stderr, "\rAnalysing position %d of %d.", done, total)
+	}
+	start := time.Now()
+	analysis, err := analyser.Analyse(context.Background(), &game)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Analysed %d moves in %s.\n", len(analysis.Plies), time.Since(start).Round(time.Millisecond))
+	for _, side := range []core.Color{core.White, core.Black} {
+		fmt.Printf("%-5s %-12s accuracy %5.1f%%, average loss %4.0f cp, %d inaccuracies, %d mistakes, %d blunders\n",
+			side.ToStr(), game.Player(side).Name(), analysis.Accuracy[side], analysis.AverageLoss[side],
+			analysis.Count(side, core.Inaccuracy), analysis.Count(side, core.Mistake), analysis.Count(side, core.Blunder))
+	}
+	for i, p := range analysis.Plies {
+		if p.Class < core.Mistake {
+			continue
+		}
+		fmt.Printf("%s on ply %d: %s, %s was best (%d cp lost)\n", p.Class.ToStr(), i+1, p.SAN, p.BestSAN, p.Loss)
+	}
+
+	pgn := analysis.ToPGN()
+	if *pgnPath == "" {
+		fmt.Println()
+		fmt.Print(pgn)
+		return 0
+	}
+	if err := os.WriteFile(*pgnPath, []byte(pgn), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,325 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ParthPant/gochess/core"
+)
+
+// handshakeTimeout is how long an engine may take to answer uci and isready.
+const handshakeTimeout = 10 * time.Second
+
+// stopTimeout is how long an engine may take to send its bestmove after stop.
+const stopTimeout = 5 * time.Second
+
+// Client runs an external UCI engine as a subprocess. It implements core.AI so that
+// the engine can be used wherever one of the built-in AIs can.
+type Client struct {
+	// Name is the name the engine reported with id name.
+	Name string
+	// Depth makes the engine search to a fixed depth when the context of GetBestMove has
+	// no deadline. Without a deadline and a depth the engine searches until ctx is done.
+	Depth int
+
+	cmd   *exec.Cmd
+	in    io.WriteCloser
+	lines chan string
+	// mu serialises the searches, the engine can only search one position at a time.
+	mu sync.Mutex
+	// dead is set when the engine stopped answering. It has been killed then and
+	// every further command fails.
+	dead      atomic.Bool
+	closeOnce sync.Once
+	closeErr  error
+}
+
+var errDead = errors.New("The engine stopped answering and has been closed.")
+
+// StartClient starts the engine at path and performs the UCI handshake.
+func StartClient(path string, args ...string) (*Client, error) {
+	cmd := exec.Command(path, args...)
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	c := &Client{
+		Name:  path,
+		cmd:   cmd,
+		in:    in,
+		lines: make(chan string, 64),
+	}
+	go func() {
+		defer close(c.lines)
+		scanner := bufio.NewScanner(out)
+		for scanner.Scan() {
+			c.lines <- scanner.Text()
+		}
+	}()
+
+	if err := c.send("uci"); err != nil {
+		c.Close()
+		return nil, err
+	}
+	err = c.waitFor(handshakeTimeout, func(line string) bool {
+		if name, ok := strings.CutPrefix(line, "id name "); ok {
+			c.Name = name
+		}
+		return line == "uciok"
+	})
+	if err == nil {
+		err = c.IsReady()
+	}
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) send(format string, args ...any) error {
+	if c.dead.Load() {
+		return errDead
+	}
+	slog.Debug("UCI send", "engine", c.Name, "command", fmt.Sprintf(format, args...))
+	_, err := fmt.Fprintf(c.in, format+"\n", args...)
+	return err
+}
+
+// waitFor reads lines from the engine until done returns true for one of them.
+func (c *Client) waitFor(timeout time.Duration, done func(line string) bool) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case line, ok := <-c.lines:
+			if !ok {
+				return errors.New("The engine has exited.")
+			}
+			if done(line) {
+				return nil
+			}
+		case <-timer.C:
+			return errors.New("The engine did not answer in time.")
+		}
+	}
+}
+
+// IsReady waits until the engine has processed all the commands sent to it.
+func (c *Client) IsReady() error {
+	if err := c.send("isready"); err != nil {
+		return err
+	}
+	return c.waitFor(handshakeTimeout, func(line string) bool {
+		return line == "readyok"
+	})
+}
+
+func (c *Client) SetOption(name string, value string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.send("setoption name %s value %s", name, value); err != nil {
+		return err
+	}
+	return c.IsReady()
+}
+
+// NewGame tells the engine that the next search belongs to a new game.
+func (c *Client) NewGame() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.send("ucinewgame"); err != nil {
+		return err
+	}
+	return c.IsReady()
+}
+
+// GetBestMove lets the engine search b. The engine is given the time until the deadline
+// of ctx, if there is one, and is stopped once ctx is done.
+func (c *Client) GetBestMove(ctx context.Context, b *core.Board, progress core.ProgressFunc) (core.SearchResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.send("position fen %s", b.ToFen()); err != nil {
+		slog.Error("Could not send the position to the engine.", "engine", c.Name, "error", err)
+		return core.SearchResult{}, false
+	}
+	var err error
+	if deadline, ok := ctx.Deadline(); ok {
+		err = c.send("go movetime %d", max(time.Until(deadline).Milliseconds(), 1))
+	} else if c.Depth > 0 {
+		err = c.send("go depth %d", c.Depth)
+	} else {
+		err = c.send("go infinite")
+	}
+	if err != nil {
+		slog.Error("Could not start the engine.", "engine", c.Name, "error", err)
+		return core.SearchResult{}, false
+	}
+
+	start := time.Now()
+	var result core.SearchResult
+	var stopTimer <-chan time.Time
+	done := ctx.Done()
+	for {
+		select {
+		case <-done:
+			// the engine gets some time to answer stop before it is given up on
+			c.send("stop")
+			done = nil
+			stopTimer = time.After(stopTimeout)
+		case <-stopTimer:
+			c.abandonSearch()
+			return result, false
+		case line, ok := <-c.lines:
+			if !ok {
+				slog.Error("The engine has exited.", "engine", c.Name)
+				return result, false
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			switch fields[0] {
+			case "info":
+				if parseInfo(b, fields[1:], &result) && progress != nil {
+					progress(result)
+				}
+			case "bestmove":
+				if len(fields) < 2 {
+					return result, false
+				}
+				move, err := b.ParseMove(fields[1])
+				if err != nil {
+					slog.Error("The engine sent an illegal move.", "engine", c.Name, "move", fields[1])
+					return result, false
+				}
+				result.Move = move
+				if len(result.PV) == 0 || result.PV[0] != move {
+					result.PV = []core.Move{move}
+				}
+				if result.Time == 0 {
+					result.Time = time.Since(start)
+				}
+				return result, true
+			}
+		}
+	}
+}
+
+// abandonSearch waits for the bestmove of a search which did not stop in time, so that it
+// is not taken as the answer to the next search. An engine which does not send it either
+// is killed.
+func (c *Client) abandonSearch() {
+	err := c.waitFor(handshakeTimeout, func(line string) bool {
+		return strings.HasPrefix(line, "bestmove")
+	})
+	if err == nil {
+		slog.Warn("The engine stopped late.", "engine", c.Name)
+		return
+	}
+	slog.Error("The engine did not stop.", "engine", c.Name, "error", err)
+	c.dead.Store(true)
+	c.cmd.Process.Kill()
+	c.Close()
+}
+
+// parseInfo updates result with an info line of the first principal variation.
+// It returns false if the line did not carry a principal variation.
+func parseInfo(b *core.Board, fields []string, result *core.SearchResult) bool {
+	hasPV := false
+	for i := 0; i < len(fields); i++ {
+		next := func() int {
+			if i+1 >= len(fields) {
+				return 0
+			}
+			i++
+			n, _ := strconv.Atoi(fields[i])
+			return n
+		}
+		switch fields[i] {
+		case "multipv":
+			if next() > 1 {
+				return false
+			}
+		case "depth":
+			result.Depth = next()
+		case "seldepth":
+			result.SelDepth = next()
+		case "nodes":
+			result.Nodes = uint64(next())
+		case "nps":
+			result.NPS = uint64(next())
+		case "time":
+			result.Time = time.Duration(next()) * time.Millisecond
+		case "score":
+			if i+2 < len(fields) {
+				kind := fields[i+1]
+				i++
+				n := next()
+				if kind == "mate" {
+					result.Score = core.MateScore(n)
+				} else if kind == "cp" {
+					result.Score = int32(n)
+				}
+			}
+		case "pv":
+			board := *b
+			var pv []core.Move
+			for _, s := range fields[i+1:] {
+				move, err := board.ParseMove(s)
+				if err != nil {
+					break
+				}
+				if board, err = board.MakeMove(move); err != nil {
+					break
+				}
+				pv = append(pv, move)
+			}
+			if len(pv) > 0 {
+				result.PV = pv
+				result.Move = pv[0]
+				hasPV = true
+			}
+			i = len(fields)
+		case "string":
+			return false
+		}
+	}
+	return hasPV
+}
+
+// Close asks the engine to quit and kills it if it does not. Only the first call has an effect.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		c.send("quit")
+		c.in.Close()
+		exited := make(chan error, 1)
+		go func() {
+			exited <- c.cmd.Wait()
+		}()
+		select {
+		case c.closeErr = <-exited:
+		case <-time.After(handshakeTimeout):
+			c.cmd.Process.Kill()
+			c.closeErr = <-exited
+		}
+	})
+	return c.closeErr
+}
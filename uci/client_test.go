@@ -0,0 +1,73 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ParthPant/gochess/core"
+)
+
+// fakeEngineEnv makes the test binary act as an engine which answers every go with the
+// bestmove in the variable.
+const fakeEngineEnv = "GOCHESS_FAKE_ENGINE_BESTMOVE"
+
+func TestMain(m *testing.M) {
+	if bestMove := os.Getenv(fakeEngineEnv); bestMove != "" {
+		runFakeEngine(bestMove)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func runFakeEngine(bestMove string) {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		switch strings.Fields(scanner.Text() + " ")[0] {
+		case "uci":
+			fmt.Println("id name fake")
+			fmt.Println("uciok")
+		case "isready":
+			fmt.Println("readyok")
+		case "go":
+			fmt.Printf("bestmove %s\n", bestMove)
+		case "quit":
+			return
+		}
+	}
+}
+
+func startFakeEngine(t *testing.T, bestMove string) *Client {
+	t.Helper()
+	t.Setenv(fakeEngineEnv, bestMove)
+	c, err := StartClient(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+	})
+	return c
+}
+
+func TestClientPromotions(t *testing.T) {
+	b, err := core.BoardFromFen("1r5k/P3P3/8/8/8/8/8/K7 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bestMove := range []string{"e7e8q", "e7e8b", "a7b8q", "a7b8n"} {
+		t.Run(bestMove, func(t *testing.T) {
+			c := startFakeEngine(t, bestMove)
+			result, ok := c.GetBestMove(context.Background(), &b, nil)
+			if !ok {
+				t.Fatal("GetBestMove failed.")
+			}
+			if result.Move.ToStr() != bestMove {
+				t.Errorf("GetBestMove returned %s, want %s.", result.Move.ToStr(), bestMove)
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+// Package uci implements the Universal Chess Interface, the protocol spoken between
+// chess engines and graphical user interfaces.
+package uci
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ParthPant/gochess/core"
+)
+
+const defaultElo = 1500
+
+// Engine reads UCI commands and answers them with a NegaMaxAI.
+type Engine struct {
+	ai    core.NegaMaxAI
+	board core.Board
+	out   io.Writer
+	outMu sync.Mutex
+
+	// search is the running search, searchDone is closed once its bestmove has been sent.
+	search     *core.SearchHandle
+	searchDone chan struct{}
+	// ponderBudget is the time the search gets after a ponderhit.
+	ponderBudget time.Duration
+
+	limitStrength bool
+	elo           int
+	skillLevel    int
+}
+
+func NewEngine(out io.Writer) *Engine {
+	board, err := core.BoardFromFen(core.StartFen)
+	if err != nil {
+		panic("Error while constructing default fen board.")
+	}
+	return &Engine{
+		ai:         core.NewNegaMaxAI(),
+		board:      board,
+		out:        out,
+		elo:        defaultElo,
+		skillLevel: core.MaxSkillLevel,
+	}
+}
+
+// Run handles the commands read from in until it receives quit or in is closed.
+func (e *Engine) Run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		if !e.handle(scanner.Text()) {
+			break
+		}
+	}
+	e.stopSearch()
+	return scanner.Err()
+}
+
+func (e *Engine) send(format string, args ...any) {
+	e.outMu.Lock()
+	defer e.outMu.Unlock()
+	fmt.Fprintf(e.out, format+"\n", args...)
+}
+
+// handle executes a single command and returns false when the engine has to quit.
+func (e *Engine) handle(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+	slog.Debug("UCI command", "command", line)
+	switch fields[0] {
+	case "uci":
+		e.send("id name gochess")
+		e.send("id author Parth Pant")
+		e.send("option name Hash type spin default %d min 1 max 1024", core.DefaultHashSize)
+		e.send("option name Ponder type check default false")
+		e.send("option name Threads type spin default 1 min 1 max 256")
+		e.send("option name MultiPV type spin default 1 min 1 max 64")
+		e.send("option name Skill Level type spin default %d min 0 max %d", core.MaxSkillLevel, core.MaxSkillLevel)
+		e.send("option name UCI_LimitStrength type check default false")
+		e.send("option name UCI_Elo type spin default %d min %d max %d", defaultElo, core.MinElo, core.MaxElo)
+		e.send("uciok")
+	case "isready":
+		e.send("readyok")
+	case "ucinewgame":
+		e.stopSearch()
+		e.ai.ClearHash()
+	case "setoption":
+		e.stopSearch()
+		e.setOption(fields[1:])
+	case "position":
+		e.stopSearch()
+		if err := e.position(fields[1:]); err != nil {
+			e.send("info string %s", err)
+		}
+	case "go":
+		e.stopSearch()
+		e.goSearch(fields[1:])
+	case "ponderhit":
+		if e.search != nil {
+			e.search.PonderHit(e.ponderBudget)
+		}
+	case "stop":
+		e.stopSearch()
+	case "quit":
+		return false
+	default:
+		e.send("info string Unknown command: %s", fields[0])
+	}
+	return true
+}
+
+func (e *Engine) setOption(args []string) {
+	// setoption name <id> [value <x>], the id may contain spaces
+	var name, value []string
+	target := &name
+	for _, arg := range args {
+		switch arg {
+		case "name":
+			target = &name
+		case "value":
+			target = &value
+		default:
+			*target = append(*target, arg)
+		}
+	}
+	id := strings.ToLower(strings.Join(name, " "))
+	v := strings.Join(value, " ")
+	n, err := strconv.Atoi(v)
+	switch id {
+	case "hash":
+		if err == nil {
+			e.ai.SetHashSize(n)
+		}
+	case "threads":
+		if err == nil {
+			e.ai.Threads = max(n, 1)
+		}
+	case "multipv":
+		if err == nil {
+			e.ai.MultiPV = max(n, 1)
+		}
+	case "skill level":
+		if err == nil {
+			e.skillLevel = n
+		}
+	case "uci_limitstrength":
+		e.limitStrength = v == "true"
+	case "uci_elo":
+		if err == nil {
+			e.elo = n
+		}
+	case "ponder":
+		// pondering is controlled by the GUI through go ponder
+		return
+	default:
+		e.send("info string Unknown option: %s", id)
+		return
+	}
+	if e.limitStrength {
+		e.ai.SetStrength(core.StrengthFromElo(e.elo))
+	} else {
+		e.ai.SetStrength(core.NewStrength(e.skillLevel))
+	}
+}
+
+// position sets up the board from "startpos" or "fen <fen>" followed by an optional list of moves.
+func (e *Engine) position(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("Missing position.")
+	}
+	movesAt := len(args)
+	for i, arg := range args {
+		if arg == "moves" {
+			movesAt = i
+			break
+		}
+	}
+
+	var board core.Board
+	var err error
+	switch args[0] {
+	case "startpos":
+		board, err = core.BoardFromFen(core.StartFen)
+	case "fen":
+		board, err = core.BoardFromFen(strings.Join(args[1:movesAt], " "))
+	default:
+		err = fmt.Errorf("Invalid position: %s", args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	if movesAt < len(args) {
+		for _, s := range args[movesAt+1:] {
+			move, err := board.ParseMove(s)
+			if err != nil {
+				return err
+			}
+			if board, err = board.MakeMove(move); err != nil {
+				return err
+			}
+		}
+	}
+	e.board = board
+	return nil
+}
+
+// goSearch starts searching the current position in the background.
+func (e *Engine) goSearch(args []string) {
+	var wtime, btime, winc, binc, movetime time.Duration
+	movesToGo := 0
+	depth := 0
+	var nodes uint64
+	infinite := false
+	ponder := false
+	for i := 0; i < len(args); i++ {
+		next := func() int {
+			if i+1 >= len(args) {
+				return 0
+			}
+			i++
+			n, _ := strconv.Atoi(args[i])
+			return n
+		}
+		switch args[i] {
+		case "wtime":
+			wtime = time.Duration(next()) * time.Millisecond
+		case "btime":
+			btime = time.Duration(next()) * time.Millisecond
+		case "winc":
+			winc = time.Duration(next()) * time.Millisecond
+		case "binc":
+			binc = time.Duration(next()) * time.Millisecond
+		case "movestogo":
+			movesToGo = next()
+		case "movetime":
+			movetime = time.Duration(next()) * time.Millisecond
+		case "depth":
+			depth = next()
+		case "nodes":
+			nodes = uint64(next())
+		case "infinite":
+			infinite = true
+		case "ponder":
+			ponder = true
+		}
+	}
+
+	remaining, increment := wtime, winc
+	if e.board.GetActiveColor() == core.Black {
+		remaining, increment = btime, binc
+	}
+	if movetime == 0 && remaining > 0 {
+		movetime = core.TimeBudget(remaining, increment, movesToGo)
+	}
+
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if movetime > 0 && !infinite && !ponder {
+		ctx, cancel = context.WithTimeout(ctx, movetime)
+	}
+	switch {
+	case depth > 0:
+		e.ai.SetDepth(uint8(min(depth, int(core.MaxDepth))))
+	case movetime > 0 || nodes > 0 || infinite:
+		e.ai.SetDepth(core.MaxDepth)
+	default:
+		e.ai.SetDepth(core.DefaultDepth)
+	}
+	e.ai.MaxNodes = nodes
+
+	if ponder {
+		// the time limit only starts with the ponderhit
+		e.ponderBudget = movetime
+		e.search = core.StartPonder(ctx, &e.ai, e.board, e.sendInfo)
+	} else {
+		e.search = core.StartSearch(ctx, &e.ai, e.board, e.sendInfo)
+	}
+	e.searchDone = make(chan struct{})
+	go func(search *core.SearchHandle, done chan struct{}) {
+		defer close(done)
+		defer cancel()
+		result, found := search.Wait()
+		if !found {
+			e.send("bestmove 0000")
+			return
+		}
+		if len(result.PV) > 1 {
+			e.send("bestmove %s ponder %s", result.Move.ToStr(), result.PV[1].ToStr())
+		} else {
+			e.send("bestmove %s", result.Move.ToStr())
+		}
+	}(e.search, e.searchDone)
+}
+
+// sendInfo reports a completed iteration of the search.
+func (e *Engine) sendInfo(result core.SearchResult) {
+	ms := result.Time.Milliseconds()
+	// a limited strength searches more lines than were asked for
+	lines := result.Lines[:min(len(result.Lines), max(e.ai.MultiPV, 1))]
+	for i, line := range lines {
+		e.send("info depth %d seldepth %d multipv %d score %s nodes %d nps %d time %d pv %s",
+			result.Depth, result.SelDepth, i+1, core.ScoreToStr(line.Score), result.Nodes, result.NPS, ms, line.PVToStr())
+	}
+}
+
+// stopSearch stops the running search and waits until its bestmove has been sent.
+func (e *Engine) stopSearch() {
+	if e.search == nil {
+		return
+	}
+	e.search.Stop()
+	<-e.searchDone
+	e.search = nil
+	e.searchDone = nil
+}
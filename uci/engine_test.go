@@ -0,0 +1,22 @@
+package uci
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEnginePositionPromotions(t *testing.T) {
+	for _, move := range []string{"e7e8q", "e7e8b", "e7e8r", "e7e8n"} {
+		t.Run(move, func(t *testing.T) {
+			e := NewEngine(io.Discard)
+			if err := e.position([]string{"fen", "7k/4P3/8/8/8/8/8/K7", "w", "-", "-", "0", "1", "moves", move}); err != nil {
+				t.Fatal(err)
+			}
+			want := "4" + strings.ToUpper(move[4:]) + "2k/8/8/8/8/8/8/K7 b - - 0 1"
+			if got := e.board.ToFen(); got != want {
+				t.Errorf("The position is %s, want %s.", got, want)
+			}
+		})
+	}
+}
@@ -2,11 +2,15 @@ package ui
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"image"
 	"image/color"
 	_ "image/png"
 	"log"
 	"log/slog"
+	"os"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
@@ -28,22 +32,34 @@ func init() {
 }
 
 type ChessGui struct {
-	chess            core.ChessGame
-	boardSize        int
+	chess     *core.ChessGame
+	boardSize int
+	// savePath is the file the game is saved to and loaded from.
+	savePath         string
 	whiteColor       color.Color
 	blackColor       color.Color
 	highlightColor   color.Color
 	pickedPiece      *core.Piece
 	pickedSquare     *core.Square
 	pickedPieceMoves *core.BitBoard
+	// shownDepth is the depth of the AI search progress shown in the title.
+	shownDepth int
+	// shownClocks are the remaining times shown in the title.
+	shownClocks string
+	// shownOffer is set while the title shows the draw offer of shownOfferBy.
+	shownOffer   bool
+	shownOfferBy core.Color
+	// shownGameOver is set while the title shows the result of the game.
+	shownGameOver bool
 }
 
-func CreateGui(chess core.ChessGame, boardSize int) ChessGui {
+func CreateGui(chess *core.ChessGame, boardSize int, savePath string) ChessGui {
 	ebiten.SetWindowSize(boardSize, boardSize)
 	ebiten.SetWindowTitle("Go Chess.")
 	return ChessGui{
 		chess:          chess,
 		boardSize:      boardSize,
+		savePath:       savePath,
 		whiteColor:     color.RGBA{0xe3, 0xc1, 0x6f, 0xff},
 		blackColor:     color.RGBA{0xb8, 0x8b, 0x4a, 0xff},
 		highlightColor: color.RGBA{0x3f, 0x7a, 0xd9, 0xff},
@@ -53,10 +69,84 @@ func CreateGui(chess core.ChessGame, boardSize int) ChessGui {
 }
 
 func (g *ChessGui) Update() error {
-	if g.chess.Board.GetActiveColor() != g.chess.HumanColor {
-		g.chess.MakeAIMove()
+	if inpututil.IsKeyJustPressed(ebiten.KeyEqual) {
+		g.changeSkillLevel(1)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyMinus) {
+		g.changeSkillLevel(-1)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyP) {
+		g.chess.SetPondering(!g.chess.IsPonderingEnabled())
+		slog.Info("Toggled pondering.", "enabled", g.chess.IsPonderingEnabled())
+		g.updateTitle()
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		if g.chess.IsClockPaused() {
+			g.chess.ResumeClock()
+		} else {
+			g.chess.PauseClock()
+		}
+		g.updateTitle()
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyLeft) {
+		g.takeBack()
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyRight) {
+		g.replay()
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyR) {
+		if side, ok := g.humanSide(); ok {
+			g.chess.Resign(side)
+		}
+		g.updateTitle()
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyD) {
+		// offers a draw, or accepts the one of the opponent
+		if side, ok := g.humanSide(); ok && !g.chess.AcceptDraw(side) {
+			g.chess.OfferDraw(side)
+		}
+		g.updateTitle()
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyC) {
+		if !g.chess.IsHumanTurn() || !g.chess.ClaimDraw() {
+			slog.Info("No draw can be claimed.")
+		}
+		g.updateTitle()
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyS) {
+		g.chess.SwapSides()
+		slog.Info("Swapped sides.", "white", g.chess.Player(core.White).Name(), "black", g.chess.Player(core.Black).Name())
+		g.updateTitle()
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyF5) {
+		g.save()
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyF9) {
+		g.load()
+	}
+	if g.chess.CheckTime() {
+		g.updateTitle()
+	}
+	if g.chess.IsGameOver() != g.shownGameOver {
+		g.updateTitle()
+	}
+	if g.chess.IsGameOver() || g.chess.IsClockPaused() {
+		return nil
+	}
+	if by, offered := g.chess.DrawOffer(); offered != g.shownOffer || by != g.shownOfferBy {
+		g.shownOffer, g.shownOfferBy = offered, by
+		g.updateTitle()
+	}
+	if clocks := g.clocksText(); clocks != g.shownClocks {
+		g.shownClocks = clocks
+		if !g.chess.IsAIThinking() {
+			g.updateTitle()
+		}
+	}
+	if !g.chess.IsHumanTurn() {
+		if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+			// move now
+			g.chess.StopAIMove()
+		}
+		if g.chess.Step() {
+			g.updateTitle()
+		} else if progress, ok := g.chess.AIProgress(); ok && progress.Depth != g.shownDepth {
+			g.shownDepth = progress.Depth
+			ebiten.SetWindowTitle(fmt.Sprintf("Go Chess.%s Thinking... depth %d [%s] %s",
+				g.shownClocks, progress.Depth, core.ScoreToStr(progress.Score), progress.PVToStr()))
+		}
 		return nil
 	}
+	g.shownDepth = 0
 	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
 		x, y := ebiten.CursorPosition()
 		p, ok := g.pieceAt(x, y)
@@ -93,6 +183,7 @@ func (g *ChessGui) Draw(screen *ebiten.Image) {
 	square := ebiten.NewImage(tileSize, tileSize)
 	hightlightSquare := ebiten.NewImage(tileSize, tileSize)
 	hightlightSquare.Fill(g.highlightColor)
+	board := g.chess.Snapshot()
 
 	for y := 0; y < 8; y++ {
 		for x := 0; x < 8; x++ {
@@ -120,7 +211,7 @@ func (g *ChessGui) Draw(screen *ebiten.Image) {
 				}
 			}
 
-			if piece, hasPiece := g.chess.Board.GetAtSq(core.SquareFromXY(x, y)); hasPiece {
+			if piece, hasPiece := board.GetAtSq(core.SquareFromXY(x, y)); hasPiece {
 				if g.pickedSquare == nil || *g.pickedSquare != core.SquareFromXY(x, y) {
 					drawPiece(uint8(piece), square)
 				}
@@ -150,6 +241,137 @@ func (g *ChessGui) GameLoop() {
 	}
 }
 
+// updateTitle shows the evaluation and the line expected by the AI in the window title.
+func (g *ChessGui) updateTitle() {
+	title := fmt.Sprintf("Go Chess. %s vs %s.", g.chess.Player(core.White).Name(), g.chess.Player(core.Black).Name())
+	if strength, ok := g.chess.GetStrength(); ok {
+		title = fmt.Sprintf("%s Skill %d.", title, strength.SkillLevel)
+	}
+	if g.chess.IsPonderingEnabled() {
+		title += " Pondering."
+	}
+	g.shownClocks = g.clocksText()
+	title += g.shownClocks
+	if g.chess.IsClockPaused() {
+		title += " Paused."
+	}
+	if by, offered := g.chess.DrawOffer(); offered {
+		title += fmt.Sprintf(" %s offers a draw.", by.ToStr())
+	}
+	g.shownGameOver = g.chess.IsGameOver()
+	if result := g.chess.Result(); result.IsOver() {
+		title += fmt.Sprintf(" %s %s", result.Outcome.ToStr(), result.Description())
+	}
+	if result, ok := g.chess.LastSearchResult(); ok {
+		title = fmt.Sprintf("%s [%s] %s", title, core.ScoreToStr(result.Score), result.PVToStr())
+	}
+	ebiten.SetWindowTitle(title)
+}
+
+// hasHuman reports whether one of the sides is played by a human at this computer.
+func (g *ChessGui) hasHuman() bool {
+	for _, side := range []core.Color{core.White, core.Black} {
+		if _, ok := g.chess.Player(side).(*core.HumanPlayer); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// humanSide returns the side of the human who uses the keyboard, the side to move
+// if it is played by a human.
+func (g *ChessGui) humanSide() (core.Color, bool) {
+	board := g.chess.Snapshot()
+	if _, ok := g.chess.Player(board.GetActiveColor()).(*core.HumanPlayer); ok {
+		return board.GetActiveColor(), true
+	}
+	for _, side := range []core.Color{core.White, core.Black} {
+		if _, ok := g.chess.Player(side).(*core.HumanPlayer); ok {
+			return side, true
+		}
+	}
+	return core.White, false
+}
+
+// takeBack undoes moves until it is the human's turn again, or a single move if no human plays.
+func (g *ChessGui) takeBack() {
+	for g.chess.CanUndo() {
+		ply := g.chess.Ply()
+		if g.chess.UndoPreviousMove(); g.chess.Ply() == ply {
+			break
+		}
+		if g.chess.IsHumanTurn() || !g.hasHuman() {
+			break
+		}
+	}
+	g.updateTitle()
+}
+
+// replay redoes undone moves until it is the human's turn again, or a single move if no human plays.
+func (g *ChessGui) replay() {
+	for g.chess.RedoMove() {
+		if g.chess.IsHumanTurn() || !g.hasHuman() {
+			break
+		}
+	}
+	g.updateTitle()
+}
+
+// clocksText returns the remaining times of both sides, or "" in games without a clock.
+func (g *ChessGui) clocksText() string {
+	clock := g.chess.Clock()
+	if clock == nil {
+		return ""
+	}
+	format := func(d time.Duration) string {
+		d = max(d, 0).Truncate(time.Second)
+		return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	return fmt.Sprintf(" White %s, Black %s.", format(clock.Remaining(core.White)), format(clock.Remaining(core.Black)))
+}
+
+// save writes the game to savePath.
+func (g *ChessGui) save() {
+	data, err := json.MarshalIndent(g.chess, "", "  ")
+	if err == nil {
+		err = os.WriteFile(g.savePath, data, 0644)
+	}
+	if err != nil {
+		slog.Error("Could not save the game.", "path", g.savePath, "error", err)
+		return
+	}
+	slog.Info("Saved the game.", "path", g.savePath)
+}
+
+// load resumes the game saved in savePath.
+func (g *ChessGui) load() {
+	data, err := os.ReadFile(g.savePath)
+	if err == nil {
+		err = g.chess.UnmarshalJSON(data)
+	}
+	if err != nil {
+		slog.Error("Could not load the game.", "path", g.savePath, "error", err)
+		return
+	}
+	slog.Info("Loaded the game.", "path", g.savePath)
+	g.pickedPiece = nil
+	g.pickedSquare = nil
+	g.pickedPieceMoves = nil
+	g.updateTitle()
+}
+
+// changeSkillLevel makes the AI stronger or weaker by delta skill levels.
+func (g *ChessGui) changeSkillLevel(delta int) {
+	strength, ok := g.chess.GetStrength()
+	if !ok {
+		return
+	}
+	strength.SkillLevel = min(max(strength.SkillLevel+delta, 0), core.MaxSkillLevel)
+	g.chess.SetStrength(strength)
+	slog.Info("Changed skill level.", "level", strength.SkillLevel)
+	g.updateTitle()
+}
+
 func (g *ChessGui) squareAt(x int, y int) core.Square {
 	tileSize := g.boardSize / 8
 	x, y = x/tileSize, y/tileSize
@@ -158,7 +380,8 @@ func (g *ChessGui) squareAt(x int, y int) core.Square {
 }
 
 func (g *ChessGui) pieceAt(x int, y int) (core.Piece, bool) {
-	return g.chess.Board.GetAtSq(g.squareAt(x, y))
+	board := g.chess.Snapshot()
+	return board.GetAtSq(g.squareAt(x, y))
 }
 
 func drawPickedPiece(piece uint8, image *ebiten.Image) {